@@ -0,0 +1,779 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"git.sofunny.io/data-analysis-public/flink-appmanager-sdk/go/pkg/client"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces
+var _ resource.Resource = &BlueGreenDeploymentResource{}
+var _ resource.ResourceWithImportState = &BlueGreenDeploymentResource{}
+var _ resource.ResourceWithValidateConfig = &BlueGreenDeploymentResource{}
+var _ resource.ResourceWithModifyPlan = &BlueGreenDeploymentResource{}
+
+const (
+	BlueGreenColorBlue  = "blue"
+	BlueGreenColorGreen = "green"
+)
+
+const (
+	// DeploymentArtifactKindJar 作业制品类型
+	DeploymentArtifactKindJar = "JAR"
+	// DefaultStabilizationSeconds 新颜色启动后默认的健康观察时长
+	DefaultStabilizationSeconds = 60
+)
+
+func NewBlueGreenDeploymentResource() resource.Resource {
+	return &BlueGreenDeploymentResource{}
+}
+
+// BlueGreenDeploymentResource 通过blue、green两个作业交替发布,实现不停机升级
+type BlueGreenDeploymentResource struct {
+	client   *client.Client
+	provider *FlinkAppManagerProviderData
+}
+
+func (r *BlueGreenDeploymentResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_blue_green_deployment"
+}
+
+func (r *BlueGreenDeploymentResource) GetSchema(_ context.Context) (tfsdk.Schema, diag.Diagnostics) {
+	return tfsdk.Schema{
+		MarkdownDescription: "Runs a job as two AppManager deployments, `<name>-blue` and `<name>-green`. " +
+			"A change starts the inactive colour from a fresh savepoint of the active one (or its latest completed savepoint " +
+			"when it is not running), waits until it is healthy " +
+			"and only then cancels the previously active colour.",
+		Attributes: map[string]tfsdk.Attribute{
+			"id": {
+				MarkdownDescription: "ID of the active deployment.",
+				Type:                types.StringType,
+				Computed:            true,
+			},
+			"namespace": {
+				Type:     types.StringType,
+				Required: true,
+				PlanModifiers: tfsdk.AttributePlanModifiers{
+					resource.RequiresReplace(),
+				},
+			},
+			"name": {
+				MarkdownDescription: "Base name of the deployments, the colour is appended as a suffix.",
+				Type:                types.StringType,
+				Required:            true,
+				PlanModifiers: tfsdk.AttributePlanModifiers{
+					resource.RequiresReplace(),
+				},
+			},
+			"deployment_target_name": {
+				MarkdownDescription: "Deployment target to run the job on. Exactly one of `deployment_target_name` and `session_cluster_name` must be set.",
+				Type:                types.StringType,
+				Optional:            true,
+			},
+			"session_cluster_name": {
+				MarkdownDescription: "Session cluster to run the job on.",
+				Type:                types.StringType,
+				Optional:            true,
+			},
+			"jar_uri": {
+				Type:     types.StringType,
+				Required: true,
+			},
+			"entry_class": {
+				Type:     types.StringType,
+				Optional: true,
+			},
+			"main_args": {
+				Type:     types.StringType,
+				Optional: true,
+			},
+			"flink_version": {
+				Type:     types.StringType,
+				Optional: true,
+			},
+			"flink_image_tag": {
+				Type:     types.StringType,
+				Optional: true,
+			},
+			"parallelism": {
+				Type:     types.Int64Type,
+				Optional: true,
+			},
+			"resources": {
+				Optional: true,
+				Attributes: tfsdk.SingleNestedAttributes(map[string]tfsdk.Attribute{
+					ResourceJobManager:  resourceSpecAttribute("Resources of the job manager."),
+					ResourceTaskManager: resourceSpecAttribute("Resources of each task manager."),
+				}),
+			},
+			"flink_configuration": {
+				Type:     types.MapType{ElemType: types.StringType},
+				Optional: true,
+			},
+			"labels": {
+				MarkdownDescription: "Labels of both deployments. Changing labels updates the active deployment in place without switching colours.",
+				Type:                types.MapType{ElemType: types.StringType},
+				Optional:            true,
+			},
+			"allow_non_restored_state": {
+				MarkdownDescription: "Allow the new colour to skip savepoint state that no longer maps to an operator.",
+				Type:                types.BoolType,
+				Optional:            true,
+			},
+			"stabilization_seconds": {
+				MarkdownDescription: fmt.Sprintf("How long the new colour must keep running without the `%s` or `%s` condition "+
+					"before the previous colour is cancelled. Defaults to `%d`.",
+					client.DeploymentConditionJobFailing, client.DeploymentConditionJobUnstable, DefaultStabilizationSeconds),
+				Type:     types.Int64Type,
+				Optional: true,
+			},
+			"max_restarts": {
+				MarkdownDescription: "Maximum number of job restarts tolerated while the new colour is stabilizing. " +
+					"By default restarts only count through the deployment conditions.",
+				Type:     types.Int64Type,
+				Optional: true,
+			},
+			"allow_stateless_start": {
+				MarkdownDescription: "Start the new colour without state when the active colour is not running and has no completed savepoint. " +
+					"Otherwise the switch fails. Defaults to `false`.",
+				Type:     types.BoolType,
+				Optional: true,
+			},
+			"deletion_policy": blueGreenDeletionPolicyAttribute(),
+			"active_color": {
+				MarkdownDescription: "Colour currently serving, `blue` or `green`.",
+				Type:                types.StringType,
+				Computed:            true,
+			},
+			"active_deployment_name": {
+				MarkdownDescription: "Name of the deployment currently serving.",
+				Type:                types.StringType,
+				Computed:            true,
+			},
+			"restored_savepoint_id": {
+				MarkdownDescription: "ID of the savepoint the active colour was started from, if any.",
+				Type:                types.StringType,
+				Computed:            true,
+			},
+			"last_savepoint_location": {
+				MarkdownDescription: "Location of the latest completed savepoint of the active colour, if any.",
+				Type:                types.StringType,
+				Computed:            true,
+			},
+		},
+	}, nil
+}
+
+// blueGreenDeletionPolicyAttribute 蓝绿作业额外支持销毁前暂停作业
+func blueGreenDeletionPolicyAttribute() tfsdk.Attribute {
+	attr := deletionPolicyAttribute(DeletionPolicyDelete, DeletionPolicySuspend, DeletionPolicyAbandon)
+	attr.MarkdownDescription += " `SUSPEND` suspends the active colour first so that AppManager takes a final savepoint, " +
+		"reports its location in a warning and then deletes both deployments."
+	return attr
+}
+
+func (r *BlueGreenDeploymentResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+	data, ok := req.ProviderData.(*FlinkAppManagerProviderData)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *FlinkAppManagerProviderData, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	r.client = data.Client
+	r.provider = data
+}
+
+// ValidateConfig 部署目标与SessionCluster必须且只能配置一个
+func (r *BlueGreenDeploymentResource) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var config BlueGreenDeploymentResourceModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &config)...)
+	if resp.Diagnostics.HasError() || config.DeploymentTargetName.Unknown || config.SessionClusterName.Unknown {
+		return
+	}
+
+	if config.DeploymentTargetName.Null == config.SessionClusterName.Null {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("deployment_target_name"),
+			"Invalid Attribute Combination",
+			"Exactly one of deployment_target_name and session_cluster_name must be set.",
+		)
+	}
+}
+
+// ModifyPlan 在计划阶段校验镜像tag,避免新颜色启动失败后才发现tag无效
+func (r *BlueGreenDeploymentResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	validateFlinkImageTag(ctx, r.client, req, resp)
+}
+
+// Create 创建作业,首次发布使用blue
+func (r *BlueGreenDeploymentResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var plan BlueGreenDeploymentResourceModel
+
+	// Read Terraform plan data into the model
+	resp.Diagnostics.Append(req.Config.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	result, err := r.SwitchColor(ctx, &plan, "")
+	if err != nil {
+		resp.Diagnostics.AddError("Error create blueGreenDeployment", "Could not create blueGreenDeployment, unexpected error: "+err.Error())
+		if result == nil {
+			return
+		}
+	}
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, result)...)
+}
+
+// Read 读取当前生效的作业
+func (r *BlueGreenDeploymentResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var state BlueGreenDeploymentResourceModel
+
+	// Read Terraform prior state data into the model
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	namespace := state.Namespace.Value
+	// 导入时根据运行中的作业确定生效的颜色
+	importing := state.ActiveColor.Value == ""
+	if importing {
+		color, err := r.findActiveColor(namespace, state.Name.Value)
+		if err != nil {
+			resp.Diagnostics.AddError("Error reading blueGreenDeployment", "Could not read blueGreenDeployment: "+err.Error())
+			return
+		}
+		state.ActiveColor = types.String{Value: color}
+		state.ActiveDeploymentName = types.String{Value: blueGreenDeploymentName(state.Name.Value, color)}
+		state.RestoredSavepointID = types.String{Null: true}
+	}
+
+	deployment, code, err := r.client.GetDeployment(state.ActiveDeploymentName.Value, namespace)
+	// 生效的作业已被删除时从状态中移除
+	if code == http.StatusNotFound {
+		resp.State.RemoveResource(ctx)
+		return
+	}
+	if err != nil {
+		resp.Diagnostics.AddError("Error reading blueGreenDeployment", "Could not read blueGreenDeployment: "+err.Error())
+		return
+	}
+	// 作业在Terraform之外被重建
+	if r.provider.identityChanged("deployment", namespace+"/"+state.ActiveDeploymentName.Value, state.ID, deployment.Metadata.Id, &resp.Diagnostics) {
+		resp.State.RemoveResource(ctx)
+		return
+	}
+	state.ID = types.String{Value: deployment.Metadata.Id}
+	r.refreshBlueGreenSpec(&state, deployment, importing)
+
+	savepoint, err := latestSavepoint(r.client, namespace, deployment.Metadata.Id)
+	if err != nil {
+		resp.Diagnostics.AddError("Error reading blueGreenDeployment", "Could not read savepoints: "+err.Error())
+		return
+	}
+	state.LastSavepointLocation = types.String{Null: true}
+	if savepoint != nil {
+		state.LastSavepointLocation = types.String{Value: savepoint.Spec.SavepointLocation}
+	}
+
+	// 作业被有意暂停或取消时无需提示
+	if deploymentDesiredState(deployment) == client.DeploymentRunning {
+		if err = deploymentHealth(deployment); err != nil {
+			resp.Diagnostics.AddWarning("Active deployment is not healthy", err.Error())
+		}
+	}
+
+	// Save updated data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, state)...)
+}
+
+// Update 作业配置变更时切换颜色发布
+func (r *BlueGreenDeploymentResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var state BlueGreenDeploymentResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var plan BlueGreenDeploymentResourceModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// 仅Provider侧属性或标签变更时无需发布,沿用当前生效的作业
+	if !r.blueGreenSpecChanged(&state, &plan) {
+		// 标签变更时直接更新生效作业的元数据,不切换颜色
+		if r.blueGreenLabelsChanged(&state, &plan) {
+			if code, err := r.updateDeploymentLabels(state.Namespace.Value, state.ActiveDeploymentName.Value, &plan); err != nil {
+				resp.Diagnostics.AddError("Error update blueGreenDeployment", "Could not update deployment labels, unexpected error: "+err.Error()+resourceVersionConflictDetail("deployment", code))
+				return
+			}
+		}
+		plan.ID = state.ID
+		plan.ActiveColor = state.ActiveColor
+		plan.ActiveDeploymentName = state.ActiveDeploymentName
+		plan.RestoredSavepointID = state.RestoredSavepointID
+		plan.LastSavepointLocation = state.LastSavepointLocation
+		resp.Diagnostics.Append(resp.State.Set(ctx, plan)...)
+		return
+	}
+
+	result, err := r.SwitchColor(ctx, &plan, state.ActiveColor.Value)
+	if err != nil {
+		resp.Diagnostics.AddError("Error update blueGreenDeployment", "Could not switch blueGreenDeployment, unexpected error: "+err.Error())
+		if result == nil {
+			return
+		}
+	}
+
+	// Save updated data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, result)...)
+}
+
+// Delete 取消并删除两种颜色的作业
+func (r *BlueGreenDeploymentResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var state BlueGreenDeploymentResourceModel
+
+	// Read Terraform prior state data into the model
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// 放弃管理时仅从状态中移除
+	if state.DeletionPolicy.Value == DeletionPolicyAbandon {
+		return
+	}
+
+	namespace := state.Namespace.Value
+	// 删除前暂停生效的作业,保留最终savepoint
+	if state.DeletionPolicy.Value == DeletionPolicySuspend {
+		name := state.ActiveDeploymentName.Value
+		location, err := r.suspendForDeletion(ctx, namespace, name)
+		if err != nil {
+			resp.Diagnostics.AddError("Error delete blueGreenDeployment", "Could not suspend deployment "+name+", unexpected error: "+err.Error())
+			return
+		}
+		if location == "" {
+			resp.Diagnostics.AddWarning("No final savepoint", fmt.Sprintf("Deployment %s has no completed savepoint, its state is not kept.", name))
+		} else {
+			resp.Diagnostics.AddWarning("Final savepoint", fmt.Sprintf("Deployment %s was suspended before deletion, its final savepoint is %s", name, location))
+		}
+	}
+
+	for _, color := range []string{BlueGreenColorBlue, BlueGreenColorGreen} {
+		name := blueGreenDeploymentName(state.Name.Value, color)
+		deployment, code, err := r.client.GetDeployment(name, namespace)
+		// 作业不存在时视为删除成功
+		if code == http.StatusNotFound {
+			continue
+		}
+		if err != nil {
+			resp.Diagnostics.AddError("Error delete blueGreenDeployment", "Could not read deployment "+name+", unexpected error: "+err.Error())
+			return
+		}
+
+		if deployment.Status == nil || deployment.Status.State != client.DeploymentCancelled {
+			if _, err = transitionDeployment(r.client, namespace, name, client.DeploymentCancelled); err != nil {
+				resp.Diagnostics.AddError("Error delete blueGreenDeployment", "Could not cancel deployment "+name+", unexpected error: "+err.Error())
+				return
+			}
+		}
+
+		_, code, err = r.client.DeleteDeployment(name, namespace)
+		if err != nil && code != http.StatusNotFound {
+			resp.Diagnostics.AddError("Error delete blueGreenDeployment", "Could not delete deployment "+name+", unexpected error: "+err.Error())
+			return
+		}
+	}
+}
+
+// suspendForDeletion 暂停运行中的作业并返回其最新完成的savepoint位置,作业不存在时返回空
+func (r *BlueGreenDeploymentResource) suspendForDeletion(ctx context.Context, namespace string, name string) (string, error) {
+	d, code, err := r.client.GetDeployment(name, namespace)
+	if code == http.StatusNotFound {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	// AppManager暂停作业时会生成savepoint
+	if d.Status != nil && d.Status.State == client.DeploymentRunning {
+		tflog.Info(ctx, "Suspending deployment before deletion", map[string]interface{}{"namespace": namespace, "deployment": name})
+		if _, err = transitionDeployment(r.client, namespace, name, client.DeploymentSuspended); err != nil {
+			return "", err
+		}
+	}
+
+	savepoint, err := latestSavepoint(r.client, namespace, d.Metadata.Id)
+	if err != nil || savepoint == nil {
+		return "", err
+	}
+	tflog.Info(ctx, "Final savepoint of deployment", map[string]interface{}{"namespace": namespace, "deployment": name, "savepoint": savepoint.Spec.SavepointLocation})
+	return savepoint.Spec.SavepointLocation, nil
+}
+
+// ImportState 导入状态
+func (r *BlueGreenDeploymentResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	idParts := strings.Split(req.ID, ",")
+
+	if len(idParts) != 2 || idParts[0] == "" || idParts[1] == "" {
+		resp.Diagnostics.AddError(
+			"Unexpected Import Identifier",
+			fmt.Sprintf("Expected import identifier with format: namespace,name. Got: %q", req.ID),
+		)
+		return
+	}
+
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("namespace"), idParts[0])...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("name"), idParts[1])...)
+}
+
+// SwitchColor 使用plan启动未生效的颜色,从生效颜色的最新savepoint恢复,健康后取消原颜色。
+// 生效颜色运行中时先为其触发savepoint,未运行时使用它最新完成的savepoint。
+// 新颜色已生效但原颜色取消失败时同时返回结果与错误
+func (r *BlueGreenDeploymentResource) SwitchColor(ctx context.Context, plan *BlueGreenDeploymentResourceModel, activeColor string) (*BlueGreenDeploymentResourceModel, error) {
+	namespace := plan.Namespace.Value
+	newColor := BlueGreenColorBlue
+	if activeColor == BlueGreenColorBlue {
+		newColor = BlueGreenColorGreen
+	}
+	newName := blueGreenDeploymentName(plan.Name.Value, newColor)
+
+	// 查询当前生效的作业,新颜色从它的状态恢复
+	var active *client.Deployment
+	activeName := blueGreenDeploymentName(plan.Name.Value, activeColor)
+	if activeColor != "" {
+		d, code, err := r.client.GetDeployment(activeName, namespace)
+		if err != nil && code != http.StatusNotFound {
+			return nil, err
+		}
+		if code != http.StatusNotFound {
+			active = d
+		}
+	}
+	// 作业被有意暂停或取消时(例如由flink_appmanager_deployment_state管理)不自动启动新颜色
+	if active != nil && deploymentDesiredState(active) != "" && deploymentDesiredState(active) != client.DeploymentRunning {
+		return nil, fmt.Errorf("%s has desired state %s, set it back to %s before changing the job so that %s is not started while the job is meant to be stopped",
+			activeName, deploymentDesiredState(active), client.DeploymentRunning, newName)
+	}
+	running := active != nil && active.Status != nil && active.Status.State == client.DeploymentRunning
+
+	// 生效的作业未运行时无法触发savepoint,使用它最新完成的savepoint
+	var restoreLocation string
+	if active != nil && !running {
+		savepoint, err := latestSavepoint(r.client, namespace, active.Metadata.Id)
+		if err != nil {
+			return nil, fmt.Errorf("list savepoints of %s failed: %v", activeName, err)
+		}
+		switch {
+		case savepoint != nil:
+			restoreLocation = savepoint.Spec.SavepointLocation
+		case plan.AllowStatelessStart.Value:
+			tflog.Warn(ctx, "Active deployment has no completed savepoint, starting without state", map[string]interface{}{"namespace": namespace, "deployment": activeName})
+		default:
+			return nil, fmt.Errorf("%s is not running and has no completed savepoint, set allow_stateless_start = true to start %s without state", activeName, newName)
+		}
+	}
+
+	restoreStrategy := client.DeploymentRestoreStrategyNone
+	if running || restoreLocation != "" {
+		restoreStrategy = client.DeploymentRestoreStrategyLatestSavepoint
+	}
+
+	// 先以取消状态创建新颜色,以便启动前挂载savepoint
+	dto := r.buildDeploymentDTO(plan, newName, client.DeploymentCancelled, restoreStrategy)
+	if err := r.preserveReusedDeployment(dto, namespace); err != nil {
+		return nil, err
+	}
+	d, _, err := r.client.CreateOrReplaceDeployment(dto, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	if running {
+		tflog.Info(ctx, "Taking savepoint of active deployment", map[string]interface{}{"namespace": namespace, "deployment": activeName})
+		savepoint, err := triggerSavepoint(r.client, namespace, active.Metadata.Id)
+		if err != nil {
+			return nil, fmt.Errorf("savepoint of %s failed: %v", activeName, err)
+		}
+		restoreLocation = savepoint.Spec.SavepointLocation
+	}
+
+	restoredSavepointID := types.String{Null: true}
+	if restoreLocation != "" {
+		savepoint, err := copySavepoint(r.client, namespace, d.Metadata.Id, restoreLocation)
+		if err != nil {
+			return nil, fmt.Errorf("copy savepoint to %s failed: %v", newName, err)
+		}
+		restoredSavepointID = types.String{Value: savepoint.Metadata.ID}
+	}
+
+	tflog.Info(ctx, "Starting deployment", map[string]interface{}{"namespace": namespace, "deployment": newName})
+	_, err = transitionDeployment(r.client, namespace, newName, client.DeploymentRunning)
+	if err == nil {
+		d, err = waitDeploymentHealthy(r.client, namespace, newName, stabilizationWindow(plan), maxRestarts(plan))
+	}
+	if err != nil {
+		// 新颜色未能稳定运行时取消新颜色,原颜色继续提供服务
+		_, _ = transitionDeployment(r.client, namespace, newName, client.DeploymentCancelled)
+		if running {
+			return nil, fmt.Errorf("%s did not become healthy, %s keeps serving: %v", newName, activeName, err)
+		}
+		return nil, fmt.Errorf("%s did not become healthy: %v", newName, err)
+	}
+
+	result := *plan
+	result.ID = types.String{Value: d.Metadata.Id}
+	result.ActiveColor = types.String{Value: newColor}
+	result.ActiveDeploymentName = types.String{Value: newName}
+	result.RestoredSavepointID = restoredSavepointID
+	result.LastSavepointLocation = types.String{Null: true}
+	if restoreLocation != "" {
+		result.LastSavepointLocation = types.String{Value: restoreLocation}
+	}
+
+	if active != nil && (active.Status == nil || active.Status.State != client.DeploymentCancelled) {
+		tflog.Info(ctx, "Cancelling previous deployment", map[string]interface{}{"namespace": namespace, "deployment": activeName})
+		if _, err = transitionDeployment(r.client, namespace, activeName, client.DeploymentCancelled); err != nil {
+			return &result, fmt.Errorf("%s is serving but %s could not be cancelled and must be cancelled manually: %v", newName, activeName, err)
+		}
+	}
+
+	return &result, nil
+}
+
+// preserveReusedDeployment 新颜色的作业已存在时,保留服务端上需要忽略的标签及注解,并携带其resourceVersion覆盖
+func (r *BlueGreenDeploymentResource) preserveReusedDeployment(dto *client.Deployment, namespace string) error {
+	existing, code, err := r.client.GetDeployment(dto.Metadata.Name, namespace)
+	if code == http.StatusNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	dto.Metadata.Labels = preserveIgnoredKeys(dto.Metadata.Labels, existing.Metadata.Labels, r.provider.IgnoreLabelPrefixes)
+	dto.Metadata.Annotations = preserveIgnoredKeys(dto.Metadata.Annotations, existing.Metadata.Annotations, r.provider.IgnoreAnnotationPrefixes)
+	dto.Metadata.ResourceVersion = existing.Metadata.ResourceVersion
+	return nil
+}
+
+// findActiveColor 查找运行中的颜色,两种颜色都未运行时返回blue
+func (r *BlueGreenDeploymentResource) findActiveColor(namespace string, name string) (string, error) {
+	for _, color := range []string{BlueGreenColorBlue, BlueGreenColorGreen} {
+		d, code, err := r.client.GetDeployment(blueGreenDeploymentName(name, color), namespace)
+		if code == http.StatusNotFound {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		if d.Status != nil && d.Status.State == client.DeploymentRunning {
+			return color, nil
+		}
+	}
+	return BlueGreenColorBlue, nil
+}
+
+// 将tf值转换成deployment请求参数
+func (r *BlueGreenDeploymentResource) buildDeploymentDTO(plan *BlueGreenDeploymentResourceModel, name string, state string, restoreStrategy string) *client.Deployment {
+	return &client.Deployment{
+		Metadata: &client.DeploymentMetadata{
+			Name:      name,
+			Namespace: plan.Namespace.Value,
+			Labels:    mergeLabels(r.provider.DefaultLabels, plan.Labels),
+		},
+		Spec: &client.DeploymentSpec{
+			State: state,
+			// 颜色之间的状态由本资源通过savepoint传递,单个作业无需有状态升级
+			UpgradeStrategy:       &client.UpgradeStrategy{Kind: client.DeploymentUpgradeStrategyStateless},
+			RestoreStrategy:       &client.RestoreStrategy{Kind: restoreStrategy, AllowNonRestoredState: plan.AllowNonRestoredState.Value},
+			DeploymentTargetIName: plan.DeploymentTargetName.Value,
+			SessionClusterName:    plan.SessionClusterName.Value,
+			Template: &client.DeploymentTemplate{
+				Spec: &client.DeploymentTemplateSpec{
+					Artifact: &client.JarArtifact{
+						Kind:          DeploymentArtifactKindJar,
+						JarUri:        plan.JarURI.Value,
+						EntryClass:    plan.EntryClass.Value,
+						MainArgs:      plan.MainArgs.Value,
+						FlinkVersion:  plan.FlinkVersion.Value,
+						FlinkImageTag: plan.FlinkImageTag.Value,
+					},
+					Parallelism:        int(plan.Parallelism.Value),
+					Resources:          buildResourcesDTO(plan.Resources),
+					FlinkConfiguration: plan.FlinkConfiguration,
+				},
+			},
+		},
+	}
+}
+
+// refreshBlueGreenSpec 使用生效作业的配置刷新状态。
+// 未配置的属性可能由AppManager填充默认值,仅在导入时读取,避免每次plan都切换颜色
+func (r *BlueGreenDeploymentResource) refreshBlueGreenSpec(state *BlueGreenDeploymentResourceModel, d *client.Deployment, importing bool) {
+	if d.Metadata != nil {
+		state.Labels = flattenLabels(d.Metadata.Labels, r.provider.DefaultLabels, state.Labels, r.provider.IgnoreLabelPrefixes)
+	}
+	if d.Spec == nil {
+		return
+	}
+
+	// 服务端可能只返回部署目标ID,未返回名称时保留状态中的值
+	if d.Spec.DeploymentTargetIName != "" {
+		state.DeploymentTargetName = types.String{Value: d.Spec.DeploymentTargetIName}
+	}
+	state.SessionClusterName = refreshString(d.Spec.SessionClusterName, state.SessionClusterName, importing)
+
+	if d.Spec.Template == nil || d.Spec.Template.Spec == nil {
+		return
+	}
+	spec := d.Spec.Template.Spec
+
+	if artifact := spec.Artifact; artifact != nil {
+		state.JarURI = types.String{Value: artifact.JarUri}
+		state.EntryClass = refreshString(artifact.EntryClass, state.EntryClass, importing)
+		state.MainArgs = refreshString(artifact.MainArgs, state.MainArgs, importing)
+		state.FlinkVersion = refreshString(artifact.FlinkVersion, state.FlinkVersion, importing)
+		state.FlinkImageTag = refreshString(artifact.FlinkImageTag, state.FlinkImageTag, importing)
+	}
+
+	if !state.Parallelism.Null || (importing && spec.Parallelism != 0) {
+		state.Parallelism = types.Int64{Value: int64(spec.Parallelism)}
+	}
+
+	state.Resources = refreshResources(spec.Resources, state.Resources, importing)
+
+	state.FlinkConfiguration = refreshFlinkConfiguration(spec.FlinkConfiguration, state.FlinkConfiguration, importing)
+}
+
+// refreshString 服务端的值为空且未配置时保持null,未配置的属性仅在导入时读取
+func refreshString(remote string, prior types.String, importing bool) types.String {
+	if prior.Null && (remote == "" || !importing) {
+		return prior
+	}
+	return types.String{Value: remote}
+}
+
+// refreshResources 仅刷新配置过的角色,未配置的角色由AppManager决定,导入时读取全部角色
+func refreshResources(remote map[string]*client.ResourceSpec, prior *SessionClusterResources, importing bool) *SessionClusterResources {
+	if prior == nil && !importing {
+		return nil
+	}
+
+	result := &SessionClusterResources{
+		JobManager:  buildResourceSpecTfValue(remote[ResourceJobManager]),
+		TaskManager: buildResourceSpecTfValue(remote[ResourceTaskManager]),
+	}
+	if importing {
+		if prior == nil && result.JobManager == nil && result.TaskManager == nil {
+			return nil
+		}
+		return result
+	}
+
+	if prior.JobManager == nil {
+		result.JobManager = nil
+	}
+	if prior.TaskManager == nil {
+		result.TaskManager = nil
+	}
+	preserveResourcesNotation(result, prior)
+	return result
+}
+
+// refreshFlinkConfiguration 仅刷新配置过的key,AppManager补充的默认配置不计入状态,导入时读取全部配置
+func refreshFlinkConfiguration(remote map[string]string, prior map[string]string, importing bool) map[string]string {
+	if importing {
+		return emptyAsConfigured(remote, prior)
+	}
+	if prior == nil {
+		return nil
+	}
+
+	result := make(map[string]string, len(prior))
+	for k := range prior {
+		if v, ok := remote[k]; ok {
+			result[k] = v
+		}
+	}
+	return result
+}
+
+// updateDeploymentLabels 覆盖作业标签并保留服务端上需要忽略的标签,作业配置不变因此不会重启作业
+func (r *BlueGreenDeploymentResource) updateDeploymentLabels(namespace string, name string, plan *BlueGreenDeploymentResourceModel) (int, error) {
+	// 携带读取到的resourceVersion覆盖,避免覆盖期间Terraform之外的修改
+	_, code, err := updateDeploymentWithRetry(r.client, namespace, name, func(d *client.Deployment) (*client.Deployment, int, error) {
+		d.Metadata.Labels = preserveIgnoredKeys(mergeLabels(r.provider.DefaultLabels, plan.Labels), d.Metadata.Labels, r.provider.IgnoreLabelPrefixes)
+		d.Status = nil
+		return r.client.CreateOrReplaceDeployment(d, namespace)
+	})
+	return code, err
+}
+
+// 判断合并Provider默认标签后的作业标签是否变更
+func (r *BlueGreenDeploymentResource) blueGreenLabelsChanged(state *BlueGreenDeploymentResourceModel, plan *BlueGreenDeploymentResourceModel) bool {
+	return !stringMapsEqual(mergeLabels(r.provider.DefaultLabels, state.Labels), mergeLabels(r.provider.DefaultLabels, plan.Labels))
+}
+
+// 判断作业配置是否变更,资源按数值语义比较,标签不参与比较
+func (r *BlueGreenDeploymentResource) blueGreenSpecChanged(state *BlueGreenDeploymentResourceModel, plan *BlueGreenDeploymentResourceModel) bool {
+	oldDTO := r.buildDeploymentDTO(state, "", "", "")
+	newDTO := r.buildDeploymentDTO(plan, "", "", "")
+
+	oldSpec, newSpec := oldDTO.Spec.Template.Spec, newDTO.Spec.Template.Spec
+	if !resourceSpecsEqual(oldSpec.Resources, newSpec.Resources) {
+		return true
+	}
+	oldSpec.Resources, newSpec.Resources = nil, nil
+
+	// 恢复策略仅在发布时生效,不参与比较
+	oldDTO.Spec.RestoreStrategy, newDTO.Spec.RestoreStrategy = nil, nil
+	return !reflect.DeepEqual(oldDTO.Spec, newDTO.Spec)
+}
+
+// 颜色对应的作业名称
+func blueGreenDeploymentName(name string, color string) string {
+	return name + "-" + color
+}
+
+// 新颜色启动后的健康观察时长
+func stabilizationWindow(plan *BlueGreenDeploymentResourceModel) time.Duration {
+	if plan.StabilizationSeconds.Null || plan.StabilizationSeconds.Unknown {
+		return DefaultStabilizationSeconds * time.Second
+	}
+	return time.Duration(plan.StabilizationSeconds.Value) * time.Second
+}
+
+// 新颜色稳定期内允许的重启次数,未配置时返回-1表示不限制
+func maxRestarts(plan *BlueGreenDeploymentResourceModel) int {
+	if plan.MaxRestarts.Null || plan.MaxRestarts.Unknown {
+		return -1
+	}
+	return int(plan.MaxRestarts.Value)
+}
@@ -0,0 +1,387 @@
+package provider
+
+import (
+	"encoding/json"
+	"fmt"
+	"git.sofunny.io/data-analysis-public/flink-appmanager-sdk/go/pkg/client"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestAccBlueGreenDeploymentResource(t *testing.T) {
+	jarURI := os.Getenv("FLINK_APPMANAGER_TEST_JAR_URI")
+	if jarURI == "" {
+		t.Skip("FLINK_APPMANAGER_TEST_JAR_URI must be set for blue/green deployment acceptance tests")
+	}
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			// Create starts the blue deployment
+			{
+				Config: testAccBlueGreenDeploymentResourceConfig(jarURI, "--version 1"),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("flink_appmanager_blue_green_deployment.test", "active_color", "blue"),
+					resource.TestCheckResourceAttr("flink_appmanager_blue_green_deployment.test", "active_deployment_name", "test-blue"),
+				),
+			},
+			// A spec change switches to green from a savepoint of blue
+			{
+				Config: testAccBlueGreenDeploymentResourceConfig(jarURI, "--version 2"),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("flink_appmanager_blue_green_deployment.test", "active_color", "green"),
+					resource.TestCheckResourceAttr("flink_appmanager_blue_green_deployment.test", "active_deployment_name", "test-green"),
+					resource.TestCheckResourceAttrSet("flink_appmanager_blue_green_deployment.test", "restored_savepoint_id"),
+				),
+			},
+			// Delete testing automatically occurs in TestCase
+		},
+	})
+}
+
+func testAccBlueGreenDeploymentResourceConfig(jarURI string, mainArgs string) string {
+	return fmt.Sprintf(`
+resource "flink_appmanager_namespace" "test" {
+  provider = fam
+
+  name =  "test"
+}
+
+resource "flink_appmanager_deployment_target" "test" {
+ provider = fam
+ depends_on = [
+  flink_appmanager_namespace.test
+ ]
+
+ name = "test"
+ namespace = flink_appmanager_namespace.test.name
+ k8s_namespace = "default"
+}
+
+resource "flink_appmanager_blue_green_deployment" "test" {
+  provider = fam
+
+  name = "test"
+  namespace = flink_appmanager_namespace.test.name
+  deployment_target_name = flink_appmanager_deployment_target.test.name
+  jar_uri = %[1]q
+  main_args = %[2]q
+  flink_version = "1.14"
+  flink_image_tag = "1.14.4-scala_2.12-java11-1"
+  parallelism = 1
+  stabilization_seconds = 30
+}
+`, jarURI, mainArgs)
+}
+
+func TestDeploymentHealth(t *testing.T) {
+	deployment := func(state string, conditions ...*client.DeploymentCondition) *client.Deployment {
+		return &client.Deployment{
+			Metadata: &client.DeploymentMetadata{Name: "test-blue"},
+			Status: &client.DeploymentStatus{
+				State:   state,
+				Running: &client.DeploymentStatusRunning{Conditions: conditions},
+			},
+		}
+	}
+
+	if err := deploymentHealth(deployment(client.DeploymentRunning)); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	if err := deploymentHealth(deployment(client.DeploymentRunning, &client.DeploymentCondition{ConditionType: client.DeploymentConditionJobFailing, Status: "False"})); err != nil {
+		t.Errorf("inactive condition reported as unhealthy: %s", err)
+	}
+	if err := deploymentHealth(deployment(client.DeploymentRunning, &client.DeploymentCondition{ConditionType: client.DeploymentConditionJobUnstable, Status: "True"})); err == nil {
+		t.Error("expected JobUnstable to be reported as unhealthy")
+	}
+	if err := deploymentHealth(deployment(client.DeploymentFailed)); err == nil {
+		t.Error("expected a failed deployment to be reported as unhealthy")
+	}
+}
+
+func TestJobRestartsWithin(t *testing.T) {
+	job := func(restarts int) *client.Job {
+		return &client.Job{Status: &client.JobStatus{Started: &client.JobStatusStarted{ObservedFlinkJobRestarts: restarts}}}
+	}
+
+	if err := jobRestartsWithin("test-blue", job(2), 2); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	if err := jobRestartsWithin("test-blue", job(3), 2); err == nil {
+		t.Error("expected restarts above the limit to be reported as unhealthy")
+	}
+	if err := jobRestartsWithin("test-blue", &client.Job{}, 0); err != nil {
+		t.Errorf("a job that has not started must not be reported: %s", err)
+	}
+}
+
+func TestBlueGreenSpecChanged(t *testing.T) {
+	r := &BlueGreenDeploymentResource{provider: &FlinkAppManagerProviderData{}}
+	state := &BlueGreenDeploymentResourceModel{
+		JarURI:    types.String{Value: "s3://jars/job-1.jar"},
+		Resources: &SessionClusterResources{TaskManager: &ResourceSpec{Cpu: types.Float64{Value: 1}, Memory: types.String{Value: "1G"}}},
+	}
+
+	plan := *state
+	plan.Resources = &SessionClusterResources{TaskManager: &ResourceSpec{Cpu: types.Float64{Value: 1}, Memory: types.String{Value: "1024m"}}}
+	plan.AllowNonRestoredState = types.Bool{Value: true}
+	plan.StabilizationSeconds = types.Int64{Value: 120}
+	if r.blueGreenSpecChanged(state, &plan) {
+		t.Error("equivalent resources and provider-side attributes must not switch colours")
+	}
+
+	plan.Labels = map[string]string{"team": "data"}
+	if r.blueGreenSpecChanged(state, &plan) {
+		t.Error("a label change must not switch colours")
+	}
+	if !r.blueGreenLabelsChanged(state, &plan) {
+		t.Error("a label change must update the deployment metadata")
+	}
+
+	plan.JarURI = types.String{Value: "s3://jars/job-2.jar"}
+	if !r.blueGreenSpecChanged(state, &plan) {
+		t.Error("a jar change must switch colours")
+	}
+}
+
+func TestUpdateDeploymentLabels(t *testing.T) {
+	var put map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut {
+			_ = json.NewDecoder(r.Body).Decode(&put)
+			_ = json.NewEncoder(w).Encode(put)
+			return
+		}
+		_, _ = w.Write([]byte(`{
+			"metadata": {"id": "d-1", "name": "test-blue", "namespace": "default", "resourceVersion": 7,
+				"labels": {"app": "old", "com.xmfunny.flink.deployment": "1"}},
+			"spec": {"state": "RUNNING", "template": {"spec": {"parallelism": 2}}},
+			"status": {"state": "RUNNING"}
+		}`))
+	}))
+	defer server.Close()
+
+	r := &BlueGreenDeploymentResource{
+		client: client.SetUp(client.Config{Endpoint: server.URL}),
+		provider: &FlinkAppManagerProviderData{
+			DefaultLabels:       map[string]string{"env": "prod"},
+			IgnoreLabelPrefixes: []string{"com.xmfunny.flink"},
+		},
+	}
+	plan := &BlueGreenDeploymentResourceModel{Labels: map[string]string{"app": "new"}}
+	if _, err := r.updateDeploymentLabels("default", "test-blue", plan); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	metadata := put["metadata"].(map[string]interface{})
+	expected := map[string]interface{}{"app": "new", "env": "prod", "com.xmfunny.flink.deployment": "1"}
+	if !reflect.DeepEqual(metadata["labels"], expected) {
+		t.Errorf("replaced labels %v, want %v", metadata["labels"], expected)
+	}
+	if metadata["resourceVersion"] != float64(7) {
+		t.Errorf("expected the read resourceVersion to be sent, got %v", metadata["resourceVersion"])
+	}
+	if _, ok := put["status"]; ok {
+		t.Error("status must not be sent")
+	}
+	if put["spec"].(map[string]interface{})["state"] != "RUNNING" {
+		t.Errorf("spec must be kept, got %v", put["spec"])
+	}
+}
+
+func TestUpdateDeploymentWithRetry(t *testing.T) {
+	resourceVersion, parallelism := 4, 2
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(fmt.Sprintf(`{
+			"metadata": {"id": "d-1", "name": "test-blue", "namespace": "default", "resourceVersion": %d},
+			"spec": {"state": "RUNNING", "template": {"spec": {"parallelism": %d}}},
+			"status": {"state": "RUNNING"}
+		}`, resourceVersion, parallelism)))
+		// the controller updates the status between two reads
+		resourceVersion++
+	}))
+	defer server.Close()
+	c := client.SetUp(client.Config{Endpoint: server.URL})
+
+	var versions []int
+	update := func(d *client.Deployment) (*client.Deployment, int, error) {
+		versions = append(versions, d.Metadata.ResourceVersion)
+		if len(versions) == 1 {
+			return nil, http.StatusConflict, fmt.Errorf("conflict")
+		}
+		return d, http.StatusOK, nil
+	}
+
+	// only the status changed on the server, the update is retried with the latest version
+	_, code, err := updateDeploymentWithRetry(c, "default", "test-blue", update)
+	if err != nil || code != http.StatusOK || !reflect.DeepEqual(versions, []int{4, 5}) {
+		t.Errorf("expected a retry with the latest version, got code %d, versions %v, error %v", code, versions, err)
+	}
+
+	// the spec was changed outside of Terraform, the conflict is returned
+	versions = nil
+	update = func(d *client.Deployment) (*client.Deployment, int, error) {
+		versions = append(versions, d.Metadata.ResourceVersion)
+		parallelism = 4
+		return nil, http.StatusConflict, fmt.Errorf("conflict")
+	}
+	_, code, err = updateDeploymentWithRetry(c, "default", "test-blue", update)
+	if err == nil || code != http.StatusConflict || len(versions) != 1 {
+		t.Errorf("expected the conflict to be returned, got code %d, versions %v, error %v", code, versions, err)
+	}
+}
+
+func TestPreserveReusedDeployment(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/namespaces/default/deployments/test-green" {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"message": "not found"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{
+			"metadata": {"id": "d-2", "name": "test-green", "namespace": "default", "resourceVersion": 9,
+				"labels": {"app": "old", "com.xmfunny.flink.deployment": "1"},
+				"annotations": {"owner": "ops", "com.xmfunny.flink.appmanager.controller.deployment.spec.version": "3"}},
+			"spec": {"state": "CANCELLED"},
+			"status": {"state": "CANCELLED"}
+		}`))
+	}))
+	defer server.Close()
+
+	r := &BlueGreenDeploymentResource{
+		client: client.SetUp(client.Config{Endpoint: server.URL}),
+		provider: &FlinkAppManagerProviderData{
+			IgnoreLabelPrefixes:      []string{"com.xmfunny.flink"},
+			IgnoreAnnotationPrefixes: []string{"com.xmfunny.flink"},
+		},
+	}
+	plan := &BlueGreenDeploymentResourceModel{Labels: map[string]string{"app": "new"}}
+
+	// the reused colour keeps the controller metadata and is replaced at its current version
+	dto := r.buildDeploymentDTO(plan, "test-green", client.DeploymentCancelled, client.DeploymentRestoreStrategyNone)
+	if err := r.preserveReusedDeployment(dto, "default"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if expected := map[string]string{"app": "new", "com.xmfunny.flink.deployment": "1"}; !reflect.DeepEqual(dto.Metadata.Labels, expected) {
+		t.Errorf("labels %v, want %v", dto.Metadata.Labels, expected)
+	}
+	if expected := map[string]string{"com.xmfunny.flink.appmanager.controller.deployment.spec.version": "3"}; !reflect.DeepEqual(dto.Metadata.Annotations, expected) {
+		t.Errorf("annotations %v, want %v", dto.Metadata.Annotations, expected)
+	}
+	if dto.Metadata.ResourceVersion != 9 {
+		t.Errorf("expected resourceVersion 9, got %d", dto.Metadata.ResourceVersion)
+	}
+
+	// a colour that does not exist yet is created as is
+	dto = r.buildDeploymentDTO(plan, "test-blue", client.DeploymentCancelled, client.DeploymentRestoreStrategyNone)
+	if err := r.preserveReusedDeployment(dto, "default"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if dto.Metadata.ResourceVersion != 0 || !reflect.DeepEqual(dto.Metadata.Labels, map[string]string{"app": "new"}) {
+		t.Errorf("new colour changed: %+v", dto.Metadata)
+	}
+}
+
+func TestRefreshBlueGreenSpec(t *testing.T) {
+	r := &BlueGreenDeploymentResource{provider: &FlinkAppManagerProviderData{}}
+	deployment := &client.Deployment{
+		Metadata: &client.DeploymentMetadata{Name: "test-blue"},
+		Spec: &client.DeploymentSpec{
+			DeploymentTargetIName: "test",
+			Template: &client.DeploymentTemplate{
+				Spec: &client.DeploymentTemplateSpec{
+					Artifact: &client.JarArtifact{
+						JarUri:       "s3://jars/job-2.jar",
+						MainArgs:     "--version 2",
+						FlinkVersion: "1.14",
+					},
+					Parallelism: 4,
+					Resources: map[string]*client.ResourceSpec{
+						ResourceJobManager:  {Cpu: 1, Memory: "1G"},
+						ResourceTaskManager: {Cpu: 2, Memory: "4G"},
+					},
+					FlinkConfiguration: map[string]string{"taskmanager.numberOfTaskSlots": "2", "state.backend": "rocksdb"},
+				},
+			},
+		},
+	}
+
+	state := BlueGreenDeploymentResourceModel{
+		DeploymentTargetName: types.String{Value: "test"},
+		SessionClusterName:   types.String{Null: true},
+		JarURI:               types.String{Value: "s3://jars/job-1.jar"},
+		EntryClass:           types.String{Null: true},
+		MainArgs:             types.String{Value: "--version 1"},
+		FlinkVersion:         types.String{Null: true},
+		FlinkImageTag:        types.String{Null: true},
+		Parallelism:          types.Int64{Value: 2},
+		Resources:            &SessionClusterResources{TaskManager: &ResourceSpec{Cpu: types.Float64{Value: 2}, Memory: types.String{Value: "4096m"}}},
+		FlinkConfiguration:   map[string]string{"taskmanager.numberOfTaskSlots": "1"},
+	}
+	r.refreshBlueGreenSpec(&state, deployment, false)
+
+	if state.JarURI.Value != "s3://jars/job-2.jar" || state.MainArgs.Value != "--version 2" || state.Parallelism.Value != 4 {
+		t.Errorf("configured attributes were not refreshed: %+v", state)
+	}
+	if !state.FlinkVersion.Null {
+		t.Errorf("unconfigured flink_version defaulted by AppManager must stay null, got %q", state.FlinkVersion.Value)
+	}
+	if state.Resources.JobManager != nil || state.Resources.TaskManager.Memory.Value != "4096m" {
+		t.Errorf("resources must only refresh configured roles and keep equivalent notation: %+v", state.Resources)
+	}
+	if len(state.FlinkConfiguration) != 1 || state.FlinkConfiguration["taskmanager.numberOfTaskSlots"] != "2" {
+		t.Errorf("flink_configuration must only refresh configured keys: %v", state.FlinkConfiguration)
+	}
+
+	imported := BlueGreenDeploymentResourceModel{
+		DeploymentTargetName: types.String{Null: true},
+		SessionClusterName:   types.String{Null: true},
+		EntryClass:           types.String{Null: true},
+		MainArgs:             types.String{Null: true},
+		FlinkVersion:         types.String{Null: true},
+		FlinkImageTag:        types.String{Null: true},
+		Parallelism:          types.Int64{Null: true},
+	}
+	r.refreshBlueGreenSpec(&imported, deployment, true)
+
+	if imported.DeploymentTargetName.Value != "test" || imported.FlinkVersion.Value != "1.14" || imported.Parallelism.Value != 4 {
+		t.Errorf("import must read the remote spec: %+v", imported)
+	}
+	if !imported.EntryClass.Null || !imported.FlinkImageTag.Null {
+		t.Error("attributes missing remotely must stay null on import")
+	}
+	if imported.Resources == nil || imported.Resources.JobManager == nil || len(imported.FlinkConfiguration) != 2 {
+		t.Errorf("import must read all resources and flink configuration: %+v", imported)
+	}
+}
+
+func TestNewestCompletedSavepoint(t *testing.T) {
+	savepoint := func(id string, state string, createdAt time.Time) client.Savepoint {
+		return client.Savepoint{
+			Metadata: &client.SavepointMetadata{ID: id, CreatedAt: &createdAt},
+			Spec:     &client.SavepointSpec{SavepointLocation: "s3://savepoints/" + id},
+			Status:   &client.SavepointStatus{State: state},
+		}
+	}
+	now := time.Now()
+
+	if sp := newestCompletedSavepoint(nil); sp != nil {
+		t.Errorf("expected no savepoint, got %s", sp.Metadata.ID)
+	}
+
+	sp := newestCompletedSavepoint([]client.Savepoint{
+		savepoint("old", client.SavepointStateCompleted, now.Add(-time.Hour)),
+		savepoint("failed", client.SavepointStateFailed, now),
+		savepoint("new", client.SavepointStateCompleted, now.Add(-time.Minute)),
+	})
+	if sp == nil || sp.Metadata.ID != "new" {
+		t.Errorf("expected the newest completed savepoint, got %+v", sp)
+	}
+}
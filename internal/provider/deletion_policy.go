@@ -0,0 +1,37 @@
+package provider
+
+import (
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"strings"
+)
+
+const (
+	// DeletionPolicyDelete 销毁时删除AppManager中的对象,未配置时的默认行为
+	DeletionPolicyDelete = "DELETE"
+	// DeletionPolicyAbandon 销毁时仅从状态中移除,保留AppManager中的对象
+	DeletionPolicyAbandon = "ABANDON"
+	// DeletionPolicyStop 销毁时仅停止SessionCluster,保留集群定义
+	DeletionPolicyStop = "STOP"
+	// DeletionPolicySuspend 销毁前暂停作业以生成最终savepoint,再删除作业
+	DeletionPolicySuspend = "SUSPEND"
+)
+
+// deletionPolicyAttribute 构建deletion_policy属性,values为资源支持的取值
+func deletionPolicyAttribute(values ...string) tfsdk.Attribute {
+	quoted := make([]string, len(values))
+	for i, v := range values {
+		quoted[i] = fmt.Sprintf("`%s`", v)
+	}
+
+	return tfsdk.Attribute{
+		MarkdownDescription: fmt.Sprintf("What happens to the object when the resource is destroyed, one of %s. ", strings.Join(quoted, ", ")) +
+			"`DELETE` (default) deletes it from AppManager, `ABANDON` only removes it from the Terraform state.",
+		Type:     types.StringType,
+		Optional: true,
+		Validators: []tfsdk.AttributeValidator{
+			stringOneOf(values...),
+		},
+	}
+}
@@ -0,0 +1,304 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"git.sofunny.io/data-analysis-public/flink-appmanager-sdk/go/pkg/client"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// getSessionClusterDeployments 查询运行在SessionCluster上的作业
+func getSessionClusterDeployments(c *client.Client, namespace string, sessionClusterName string) ([]client.Deployment, error) {
+	deployments, _, err := c.GetDeployments(nil, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []client.Deployment
+	for _, d := range deployments {
+		if d.Spec != nil && d.Spec.SessionClusterName == sessionClusterName {
+			result = append(result, d)
+		}
+	}
+	return result, nil
+}
+
+// transitionDeployment 修改作业期望状态并等待状态扭转完成
+func transitionDeployment(c *client.Client, namespace string, name string, state string) (*client.Deployment, error) {
+	_, code, err := updateDeploymentWithRetry(c, namespace, name, func(current *client.Deployment) (*client.Deployment, int, error) {
+		d := &client.Deployment{
+			Metadata: &client.DeploymentMetadata{Name: name, Namespace: namespace, ResourceVersion: current.Metadata.ResourceVersion},
+			Spec:     &client.DeploymentSpec{State: state},
+		}
+		return c.UpdateDeployment(d, namespace)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%v%s", err, resourceVersionConflictDetail("deployment", code))
+	}
+
+	d, _, err := c.WaitDeploymentStateChange(name, state, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	return d, nil
+}
+
+// updateDeploymentWithRetry 读取作业后携带其resourceVersion更新。版本冲突时若作业配置仍与首次读取时一致
+// (如仅控制器更新了作业状态),使用最新的resourceVersion重试,否则返回冲突
+func updateDeploymentWithRetry(c *client.Client, namespace string, name string, update func(current *client.Deployment) (*client.Deployment, int, error)) (*client.Deployment, int, error) {
+	var expected []byte
+	for attempt := 1; ; attempt++ {
+		current, code, err := c.GetDeployment(name, namespace)
+		if err != nil {
+			return nil, code, err
+		}
+		spec, err := json.Marshal(current.Spec)
+		if err != nil {
+			return nil, http.StatusInternalServerError, err
+		}
+		if expected != nil && !bytes.Equal(expected, spec) {
+			return nil, http.StatusConflict, fmt.Errorf("deployment %s was modified while it was being updated", name)
+		}
+		expected = spec
+
+		d, code, err := update(current)
+		if code != http.StatusConflict || attempt >= ResourceVersionConflictRetries {
+			return d, code, err
+		}
+	}
+}
+
+// deploymentStates 记录批量操作中每个作业最终所处的状态,用于输出失败汇总
+type deploymentStates struct {
+	names  []string
+	states map[string]string
+}
+
+func newDeploymentStates() *deploymentStates {
+	return &deploymentStates{states: make(map[string]string)}
+}
+
+func (s *deploymentStates) set(name string, state string) {
+	if _, ok := s.states[name]; !ok {
+		s.names = append(s.names, name)
+	}
+	s.states[name] = state
+}
+
+func (s *deploymentStates) String() string {
+	if len(s.names) == 0 {
+		return "no deployments were touched"
+	}
+
+	lines := make([]string, len(s.names))
+	for i, name := range s.names {
+		lines[i] = fmt.Sprintf("  - %s: %s", name, s.states[name])
+	}
+	return "deployment states:\n" + strings.Join(lines, "\n")
+}
+
+// getDeploymentTargetDependants 查询引用部署目标的作业及SessionCluster
+func getDeploymentTargetDependants(c *client.Client, namespace string, id string, name string) ([]string, error) {
+	deployments, _, err := c.GetDeployments(nil, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	var dependants []string
+	for _, d := range deployments {
+		if d.Spec == nil || d.Metadata == nil {
+			continue
+		}
+		if d.Spec.DeploymentTargetIName == name || (id != "" && d.Spec.DeploymentTargetId == id) {
+			dependants = append(dependants, "deployment "+d.Metadata.Name)
+		}
+	}
+
+	sessionClusters, _, err := c.GetSessionClusters(namespace)
+	if err != nil {
+		return nil, err
+	}
+	for _, sc := range sessionClusters {
+		if sc.Spec != nil && sc.Metadata != nil && sc.Spec.DeploymentTargetName == name {
+			dependants = append(dependants, "sessionCluster "+sc.Metadata.Name)
+		}
+	}
+	return dependants, nil
+}
+
+// getSessionClusterDependants 查询运行在SessionCluster上的作业名称
+func getSessionClusterDependants(c *client.Client, namespace string, name string) ([]string, error) {
+	deployments, err := getSessionClusterDeployments(c, namespace, name)
+	if err != nil {
+		return nil, err
+	}
+
+	var dependants []string
+	for _, d := range deployments {
+		if d.Metadata != nil {
+			dependants = append(dependants, "deployment "+d.Metadata.Name)
+		}
+	}
+	return dependants, nil
+}
+
+// dependantsDetail 输出仍在引用对象的依赖列表
+func dependantsDetail(dependants []string) string {
+	lines := make([]string, len(dependants))
+	for i, d := range dependants {
+		lines[i] = "  - " + d
+	}
+	return "still referenced by:\n" + strings.Join(lines, "\n") + "\n\nRemove them first or set force_destroy = true."
+}
+
+// triggerSavepoint 为运行中的作业触发savepoint并等待完成
+func triggerSavepoint(c *client.Client, namespace string, deploymentID string) (*client.Savepoint, error) {
+	savepoint := &client.Savepoint{
+		Metadata: &client.SavepointMetadata{Namespace: namespace, DeploymentID: deploymentID},
+	}
+	savepoint, _, err := c.CreateSavepoint(savepoint, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	return waitSavepointCompleted(c, namespace, savepoint.Metadata.ID)
+}
+
+// copySavepoint 将savepoint复制给指定作业,作业使用LATEST_SAVEPOINT策略时从该savepoint恢复
+func copySavepoint(c *client.Client, namespace string, deploymentID string, savepointLocation string) (*client.Savepoint, error) {
+	savepoint := &client.Savepoint{
+		Metadata: &client.SavepointMetadata{Namespace: namespace, DeploymentID: deploymentID, Origin: client.SavepointOriginCopied},
+		Spec:     &client.SavepointSpec{SavepointLocation: savepointLocation},
+	}
+	savepoint, _, err := c.CreateSavepoint(savepoint, namespace)
+	if err != nil {
+		return nil, err
+	}
+	return savepoint, nil
+}
+
+// latestSavepoint 查询作业最新完成的savepoint,不存在时返回nil
+func latestSavepoint(c *client.Client, namespace string, deploymentID string) (*client.Savepoint, error) {
+	savepoints, _, err := c.GetSavepoints(deploymentID, "", "", namespace)
+	if err != nil {
+		return nil, err
+	}
+	return newestCompletedSavepoint(savepoints), nil
+}
+
+// newestCompletedSavepoint 从列表中选出创建时间最新且已完成的savepoint
+func newestCompletedSavepoint(savepoints []client.Savepoint) *client.Savepoint {
+	var latest *client.Savepoint
+	for i := range savepoints {
+		sp := &savepoints[i]
+		if sp.Metadata == nil || sp.Spec == nil || sp.Spec.SavepointLocation == "" ||
+			sp.Status == nil || sp.Status.State != client.SavepointStateCompleted {
+			continue
+		}
+		if latest == nil || (sp.Metadata.CreatedAt != nil && (latest.Metadata.CreatedAt == nil || sp.Metadata.CreatedAt.After(*latest.Metadata.CreatedAt))) {
+			latest = sp
+		}
+	}
+	return latest
+}
+
+// waitSavepointCompleted 等待savepoint完成,savepoint失败时返回失败原因
+func waitSavepointCompleted(c *client.Client, namespace string, savepointID string) (*client.Savepoint, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.Cfg.Timeout)
+	defer cancel()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("wait savepoint %s completed: %v", savepointID, ctx.Err())
+		case <-time.After(c.Cfg.Interval):
+			savepoint, _, err := c.GetSavepoint(savepointID, namespace)
+			if err != nil {
+				return nil, err
+			}
+			if savepoint.Status == nil {
+				continue
+			}
+
+			switch savepoint.Status.State {
+			case client.SavepointStateCompleted:
+				return savepoint, nil
+			case client.SavepointStateFailed:
+				reason := "unknown reason"
+				if savepoint.Status.Failure != nil {
+					reason = savepoint.Status.Failure.Message
+				}
+				return nil, fmt.Errorf("savepoint %s failed: %s", savepointID, reason)
+			}
+		}
+	}
+}
+
+// waitDeploymentHealthy 在观察窗口内持续检查作业,作业不再运行、出现JobFailing、JobUnstable
+// 或重启次数超过maxRestarts时返回错误,maxRestarts小于0时不限制重启次数
+func waitDeploymentHealthy(c *client.Client, namespace string, name string, window time.Duration, maxRestarts int) (*client.Deployment, error) {
+	deadline := time.Now().Add(window)
+	for {
+		d, _, err := c.GetDeployment(name, namespace)
+		if err != nil {
+			return nil, err
+		}
+		if err = deploymentHealth(d); err != nil {
+			return d, err
+		}
+		if maxRestarts >= 0 && d.Status.Running != nil && d.Status.Running.JobId != "" {
+			job, _, err := c.GetJobByID(d.Status.Running.JobId, namespace)
+			if err != nil {
+				return d, err
+			}
+			if err = jobRestartsWithin(d.Metadata.Name, job, maxRestarts); err != nil {
+				return d, err
+			}
+		}
+
+		if !time.Now().Before(deadline) {
+			return d, nil
+		}
+		time.Sleep(c.Cfg.Interval)
+	}
+}
+
+// deploymentHealth 根据作业状态及Conditions判断作业是否健康
+func deploymentHealth(d *client.Deployment) error {
+	if d.Status == nil || d.Status.State != client.DeploymentRunning {
+		state := "unknown"
+		if d.Status != nil {
+			state = d.Status.State
+		}
+		return fmt.Errorf("deployment %s is %s", d.Metadata.Name, state)
+	}
+
+	if d.Status.Running == nil {
+		return nil
+	}
+	for _, condition := range d.Status.Running.Conditions {
+		if condition == nil || condition.Status != "True" {
+			continue
+		}
+		if condition.ConditionType == client.DeploymentConditionJobFailing || condition.ConditionType == client.DeploymentConditionJobUnstable {
+			return fmt.Errorf("deployment %s is unhealthy, %s: %s", d.Metadata.Name, condition.ConditionType, condition.Message)
+		}
+	}
+	return nil
+}
+
+// jobRestartsWithin 判断作业重启次数是否超过上限
+func jobRestartsWithin(name string, job *client.Job, maxRestarts int) error {
+	if job == nil || job.Status == nil || job.Status.Started == nil {
+		return nil
+	}
+	if restarts := job.Status.Started.ObservedFlinkJobRestarts; restarts > maxRestarts {
+		return fmt.Errorf("deployment %s restarted %d times, more than the allowed %d", name, restarts, maxRestarts)
+	}
+	return nil
+}
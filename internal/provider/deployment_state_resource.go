@@ -0,0 +1,300 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"git.sofunny.io/data-analysis-public/flink-appmanager-sdk/go/pkg/client"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"net/http"
+	"strings"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces
+var _ resource.Resource = &DeploymentStateResource{}
+var _ resource.ResourceWithImportState = &DeploymentStateResource{}
+
+func NewDeploymentStateResource() resource.Resource {
+	return &DeploymentStateResource{}
+}
+
+// DeploymentStateResource 仅管理已有作业的期望状态
+type DeploymentStateResource struct {
+	client   *client.Client
+	provider *FlinkAppManagerProviderData
+}
+
+func (r *DeploymentStateResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_deployment_state"
+}
+
+func (r *DeploymentStateResource) GetSchema(_ context.Context) (tfsdk.Schema, diag.Diagnostics) {
+	return tfsdk.Schema{
+		MarkdownDescription: "Owns only the desired state of an existing deployment, so that it can be suspended or cancelled " +
+			"without changing the configuration that manages its spec. The previous state is restored on destroy. " +
+			"While the state is not `RUNNING`, `flink_appmanager_blue_green_deployment` refuses to switch colours instead of starting the job. " +
+			"When the deployment is recreated or its state is changed elsewhere, for example by a colour switch, " +
+			"`previous_state` is cleared and further transitions are refused until the resource is replaced.",
+		Attributes: map[string]tfsdk.Attribute{
+			"id": {
+				Type:     types.StringType,
+				Computed: true,
+				PlanModifiers: tfsdk.AttributePlanModifiers{
+					resource.UseStateForUnknown(),
+				},
+			},
+			"namespace": {
+				Type:     types.StringType,
+				Required: true,
+				PlanModifiers: tfsdk.AttributePlanModifiers{
+					resource.RequiresReplace(),
+				},
+			},
+			"deployment_name": {
+				Type:     types.StringType,
+				Required: true,
+				PlanModifiers: tfsdk.AttributePlanModifiers{
+					resource.RequiresReplace(),
+				},
+			},
+			"state": {
+				MarkdownDescription: "Desired state of the deployment.",
+				Type:                types.StringType,
+				Required:            true,
+				Validators: []tfsdk.AttributeValidator{
+					stringOneOf(client.DeploymentRunning, client.DeploymentSuspended, client.DeploymentCancelled),
+				},
+			},
+			"previous_state": {
+				MarkdownDescription: "Desired state of the deployment before this resource was created, restored on destroy. " +
+					"Cleared when the deployment was changed outside of this resource.",
+				Type:     types.StringType,
+				Computed: true,
+				PlanModifiers: tfsdk.AttributePlanModifiers{
+					resource.UseStateForUnknown(),
+				},
+			},
+		},
+	}, nil
+}
+
+func (r *DeploymentStateResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+	data, ok := req.ProviderData.(*FlinkAppManagerProviderData)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *FlinkAppManagerProviderData, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	r.client = data.Client
+	r.provider = data
+}
+
+// Create 记录作业当前的期望状态并扭转到配置的状态
+func (r *DeploymentStateResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var plan DeploymentStateResourceModel
+
+	// Read Terraform plan data into the model
+	resp.Diagnostics.Append(req.Config.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	namespace, name := plan.Namespace.Value, plan.DeploymentName.Value
+	deployment, _, err := r.client.GetDeployment(name, namespace)
+	if err != nil {
+		resp.Diagnostics.AddError("Error create deploymentState", "Could not read deployment, unexpected error: "+err.Error())
+		return
+	}
+	previousState := deploymentDesiredState(deployment)
+
+	if previousState != plan.State.Value {
+		deployment, err = transitionDeployment(r.client, namespace, name, plan.State.Value)
+		if err != nil {
+			resp.Diagnostics.AddError("Error create deploymentState", "Could not transition deployment, unexpected error: "+err.Error())
+			return
+		}
+	}
+
+	var result = DeploymentStateResourceModel{
+		ID:             types.String{Value: deployment.Metadata.Id},
+		Namespace:      plan.Namespace,
+		DeploymentName: plan.DeploymentName,
+		State:          plan.State,
+		PreviousState:  types.String{Value: previousState},
+	}
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, result)...)
+}
+
+// Read 读取作业的期望状态
+func (r *DeploymentStateResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var state DeploymentStateResourceModel
+
+	// Read Terraform prior state data into the model
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	deployment, code, err := r.client.GetDeployment(state.DeploymentName.Value, state.Namespace.Value)
+	// 作业已被删除时从状态中移除
+	if code == http.StatusNotFound {
+		resp.State.RemoveResource(ctx)
+		return
+	}
+	if err != nil {
+		resp.Diagnostics.AddError("Error reading deploymentState", "Could not read deployment: "+err.Error())
+		return
+	}
+	// 作业在Terraform之外被重建
+	if r.provider.identityChanged("deployment", state.Namespace.Value+"/"+state.DeploymentName.Value, state.ID, deployment.Metadata.Id, &resp.Diagnostics) {
+		resp.State.RemoveResource(ctx)
+		return
+	}
+
+	// 导入时以当前状态作为销毁时恢复的状态
+	importing := state.State.Null
+	// 作业在本资源之外被修改(例如蓝绿切换取消了该颜色)时不再恢复previous_state
+	if reason := deploymentChangedOutside(&state, deployment); reason != "" && !state.PreviousState.Null {
+		resp.Diagnostics.AddWarning("Deployment changed outside flink_appmanager_deployment_state",
+			reason+". previous_state is cleared so that it is not restored, and this resource refuses further transitions until it is replaced.")
+		state.PreviousState = types.String{Null: true}
+	}
+
+	state.ID = types.String{Value: deployment.Metadata.Id}
+	state.State = types.String{Value: deploymentDesiredState(deployment)}
+	if importing {
+		state.PreviousState = state.State
+	}
+
+	// Save updated data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, state)...)
+}
+
+// Update 扭转作业状态
+func (r *DeploymentStateResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var state DeploymentStateResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var plan DeploymentStateResourceModel
+
+	// Read Terraform plan data into the model
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// 作业在本资源之外被修改后拒绝扭转,避免用过期的savepoint重新启动
+	deployment, _, err := r.client.GetDeployment(plan.DeploymentName.Value, plan.Namespace.Value)
+	if err != nil {
+		resp.Diagnostics.AddError("Error update deploymentState", "Could not read deployment, unexpected error: "+err.Error())
+		return
+	}
+	reason := deploymentChangedOutside(&state, deployment)
+	if reason == "" && state.PreviousState.Null {
+		reason = "deployment " + plan.DeploymentName.Value + " was changed after previous_state was captured"
+	}
+	if reason != "" {
+		resp.Diagnostics.AddError("Error update deploymentState", "Refusing to transition deployment: "+reason+
+			". Replace this resource to manage the current deployment again.")
+		return
+	}
+
+	_, err = transitionDeployment(r.client, plan.Namespace.Value, plan.DeploymentName.Value, plan.State.Value)
+	if err != nil {
+		resp.Diagnostics.AddError("Error update deploymentState", "Could not transition deployment, unexpected error: "+err.Error())
+		return
+	}
+
+	// Save updated data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, plan)...)
+}
+
+// Delete 恢复作业之前的期望状态
+func (r *DeploymentStateResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var state DeploymentStateResourceModel
+
+	// Read Terraform prior state data into the model
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	namespace, name := state.Namespace.Value, state.DeploymentName.Value
+	deployment, code, err := r.client.GetDeployment(name, namespace)
+	// 作业已被删除时无需恢复
+	if code == http.StatusNotFound {
+		return
+	}
+	if err != nil {
+		resp.Diagnostics.AddError("Error delete deploymentState", "Could not read deployment, unexpected error: "+err.Error())
+		return
+	}
+
+	previousState := state.PreviousState.Value
+	if previousState == "" || deploymentDesiredState(deployment) == previousState {
+		return
+	}
+	// 作业在本资源之外被修改后不再恢复,避免重新启动已被替换的作业
+	if reason := deploymentChangedOutside(&state, deployment); reason != "" {
+		resp.Diagnostics.AddWarning("Deployment state not restored", reason+", previous_state "+previousState+" is not restored.")
+		return
+	}
+
+	_, err = transitionDeployment(r.client, namespace, name, previousState)
+	if err != nil {
+		resp.Diagnostics.AddError("Error delete deploymentState", "Could not restore deployment state "+previousState+", unexpected error: "+err.Error())
+		return
+	}
+}
+
+// ImportState 导入状态
+func (r *DeploymentStateResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	idParts := strings.Split(req.ID, ",")
+
+	if len(idParts) != 2 || idParts[0] == "" || idParts[1] == "" {
+		resp.Diagnostics.AddError(
+			"Unexpected Import Identifier",
+			fmt.Sprintf("Expected import identifier with format: namespace,deploymentName. Got: %q", req.ID),
+		)
+		return
+	}
+
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("namespace"), idParts[0])...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("deployment_name"), idParts[1])...)
+}
+
+// 作业的期望状态
+func deploymentDesiredState(d *client.Deployment) string {
+	if d.Spec == nil {
+		return ""
+	}
+	return d.Spec.State
+}
+
+// deploymentChangedOutside 判断作业在本资源最后一次扭转之后是否被重建或修改了期望状态,返回原因
+func deploymentChangedOutside(state *DeploymentStateResourceModel, d *client.Deployment) string {
+	if !state.ID.Null && state.ID.Value != "" && d.Metadata != nil && d.Metadata.Id != state.ID.Value {
+		return fmt.Sprintf("deployment %s was recreated with ID %s", state.DeploymentName.Value, d.Metadata.Id)
+	}
+	if !state.State.Null && state.State.Value != "" && deploymentDesiredState(d) != state.State.Value {
+		return fmt.Sprintf("deployment %s was moved to %s outside of this resource", state.DeploymentName.Value, deploymentDesiredState(d))
+	}
+	return ""
+}
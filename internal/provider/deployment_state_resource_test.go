@@ -0,0 +1,133 @@
+package provider
+
+import (
+	"fmt"
+	"git.sofunny.io/data-analysis-public/flink-appmanager-sdk/go/pkg/client"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"os"
+	"regexp"
+	"testing"
+)
+
+func TestAccDeploymentStateResource(t *testing.T) {
+	jarURI := os.Getenv("FLINK_APPMANAGER_TEST_JAR_URI")
+	if jarURI == "" {
+		t.Skip("FLINK_APPMANAGER_TEST_JAR_URI must be set for deployment state acceptance tests")
+	}
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			// Create suspends the running deployment and remembers its state
+			{
+				Config: testAccDeploymentStateResourceConfig(jarURI, "--version 1", "SUSPENDED"),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("flink_appmanager_deployment_state.test", "state", "SUSPENDED"),
+					resource.TestCheckResourceAttr("flink_appmanager_deployment_state.test", "previous_state", "RUNNING"),
+				),
+			},
+			// The blue/green resource refuses to start a new colour while the job is suspended
+			{
+				Config:      testAccDeploymentStateResourceConfig(jarURI, "--version 2", "SUSPENDED"),
+				ExpectError: regexp.MustCompile("has desired state SUSPENDED"),
+			},
+			// Update transitions the deployment without touching previous_state
+			{
+				Config: testAccDeploymentStateResourceConfig(jarURI, "--version 1", "CANCELLED"),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("flink_appmanager_deployment_state.test", "state", "CANCELLED"),
+					resource.TestCheckResourceAttr("flink_appmanager_deployment_state.test", "previous_state", "RUNNING"),
+				),
+			},
+			// Resume the deployment so that the blue/green resource may switch colours again
+			{
+				Config: testAccDeploymentStateResourceConfig(jarURI, "--version 1", "RUNNING"),
+				Check:  resource.TestCheckResourceAttr("flink_appmanager_deployment_state.test", "state", "RUNNING"),
+			},
+			// The switch cancels test-blue behind this resource's back
+			{
+				Config:             testAccDeploymentStateResourceConfig(jarURI, "--version 2", "RUNNING"),
+				Check:              resource.TestCheckResourceAttr("flink_appmanager_blue_green_deployment.test", "active_color", "green"),
+				ExpectNonEmptyPlan: true,
+			},
+			// Refresh detaches the resource instead of planning to restart the cancelled colour
+			{
+				Config:             testAccDeploymentStateResourceConfig(jarURI, "--version 2", "RUNNING"),
+				PlanOnly:           true,
+				ExpectNonEmptyPlan: true,
+			},
+			{
+				Config:      testAccDeploymentStateResourceConfig(jarURI, "--version 2", "RUNNING"),
+				ExpectError: regexp.MustCompile("Refusing to transition deployment"),
+			},
+			// Destroying the detached resource does not restore previous_state
+			{
+				Config: testAccBlueGreenDeploymentResourceConfig(jarURI, "--version 2"),
+				Check:  testAccCheckDeploymentDesiredState("test", "test-blue", "CANCELLED"),
+			},
+			// Delete testing automatically occurs in TestCase
+		},
+	})
+}
+
+func testAccCheckDeploymentDesiredState(namespace string, name string, expected string) resource.TestCheckFunc {
+	return func(_ *terraform.State) error {
+		c := client.SetUp(client.Config{Endpoint: os.Getenv("FLINK_APPMANAGER_ENDPOINT")})
+		d, _, err := c.GetDeployment(name, namespace)
+		if err != nil {
+			return err
+		}
+		if state := deploymentDesiredState(d); state != expected {
+			return fmt.Errorf("deployment %s has desired state %s, expected %s", name, state, expected)
+		}
+		return nil
+	}
+}
+
+func TestDeploymentChangedOutside(t *testing.T) {
+	state := &DeploymentStateResourceModel{
+		ID:             types.String{Value: "d-1"},
+		DeploymentName: types.String{Value: "test-blue"},
+		State:          types.String{Value: client.DeploymentRunning},
+	}
+	deployment := func(id string, desired string) *client.Deployment {
+		return &client.Deployment{
+			Metadata: &client.DeploymentMetadata{Id: id},
+			Spec:     &client.DeploymentSpec{State: desired},
+		}
+	}
+
+	if reason := deploymentChangedOutside(state, deployment("d-1", client.DeploymentRunning)); reason != "" {
+		t.Errorf("unexpected change reported: %s", reason)
+	}
+	if reason := deploymentChangedOutside(state, deployment("d-1", client.DeploymentCancelled)); reason == "" {
+		t.Error("expected a desired state changed elsewhere to be reported")
+	}
+	if reason := deploymentChangedOutside(state, deployment("d-2", client.DeploymentRunning)); reason == "" {
+		t.Error("expected a recreated deployment to be reported")
+	}
+
+	imported := &DeploymentStateResourceModel{ID: types.String{Null: true}, State: types.String{Null: true}}
+	if reason := deploymentChangedOutside(imported, deployment("d-1", client.DeploymentSuspended)); reason != "" {
+		t.Errorf("unexpected change reported on import: %s", reason)
+	}
+}
+
+func testAccDeploymentStateResourceConfig(jarURI string, mainArgs string, state string) string {
+	// Reference the deployment by name rather than active_deployment_name, which changes on every switch
+	return testAccBlueGreenDeploymentResourceConfig(jarURI, mainArgs) + fmt.Sprintf(`
+resource "flink_appmanager_deployment_state" "test" {
+  provider = fam
+  depends_on = [
+    flink_appmanager_blue_green_deployment.test
+  ]
+
+  namespace = flink_appmanager_namespace.test.name
+  deployment_name = "test-blue"
+  state = %[1]q
+}
+`, state)
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/hashicorp/terraform-plugin-framework/resource"
 	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
 	"github.com/hashicorp/terraform-plugin-framework/types"
+	"net/http"
 	"strings"
 )
 
@@ -25,7 +26,8 @@ func NewDeploymentTargetResource() resource.Resource {
 
 // DeploymentTargetResource defines the resource implementation.
 type DeploymentTargetResource struct {
-	client *client.Client
+	client   *client.Client
+	provider *FlinkAppManagerProviderData
 }
 
 func (r *DeploymentTargetResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
@@ -38,14 +40,23 @@ func (r *DeploymentTargetResource) GetSchema(_ context.Context) (tfsdk.Schema, d
 			"id": {
 				Type:     types.StringType,
 				Computed: true,
+				PlanModifiers: tfsdk.AttributePlanModifiers{
+					resource.UseStateForUnknown(),
+				},
 			},
 			"namespace": {
 				Type:     types.StringType,
 				Required: true,
+				PlanModifiers: tfsdk.AttributePlanModifiers{
+					resource.RequiresReplace(),
+				},
 			},
 			"name": {
 				Type:     types.StringType,
 				Required: true,
+				PlanModifiers: tfsdk.AttributePlanModifiers{
+					resource.RequiresReplace(),
+				},
 			},
 			"k8s_namespace": {
 				Type:     types.StringType,
@@ -53,8 +64,38 @@ func (r *DeploymentTargetResource) GetSchema(_ context.Context) (tfsdk.Schema, d
 				Computed: true,
 				PlanModifiers: tfsdk.AttributePlanModifiers{
 					resource.UseStateForUnknown(),
+					resource.RequiresReplace(),
 				},
 			},
+			"labels": {
+				MarkdownDescription: "Labels of the deployment target. Changing labels replaces the deployment target. " +
+					"Keys of the provider `default_labels` are not tracked, so changing `default_labels` neither replaces nor updates existing deployment targets.",
+				Type:     types.MapType{ElemType: types.StringType},
+				Optional: true,
+				PlanModifiers: tfsdk.AttributePlanModifiers{
+					resource.RequiresReplace(),
+				},
+			},
+			"annotations": {
+				MarkdownDescription: "Annotations of the deployment target. Changing annotations replaces the deployment target.",
+				Type:                types.MapType{ElemType: types.StringType},
+				Optional:            true,
+				PlanModifiers: tfsdk.AttributePlanModifiers{
+					resource.RequiresReplace(),
+				},
+			},
+			"adopt_existing": {
+				MarkdownDescription: "Take an existing deployment target with the same name into state instead of failing on create. " +
+					"Adoption fails with the list of differences when the existing deployment target does not match the configuration.",
+				Type:     types.BoolType,
+				Optional: true,
+			},
+			"deletion_policy": deletionPolicyAttribute(DeletionPolicyDelete, DeletionPolicyAbandon),
+			"force_destroy": {
+				MarkdownDescription: "Delete the deployment target even if deployments or session clusters still reference it.",
+				Type:                types.BoolType,
+				Optional:            true,
+			},
 		},
 	}, nil
 }
@@ -64,18 +105,19 @@ func (r *DeploymentTargetResource) Configure(ctx context.Context, req resource.C
 	if req.ProviderData == nil {
 		return
 	}
-	c, ok := req.ProviderData.(*client.Client)
+	data, ok := req.ProviderData.(*FlinkAppManagerProviderData)
 
 	if !ok {
 		resp.Diagnostics.AddError(
 			"Unexpected Resource Configure Type",
-			fmt.Sprintf("Expected *http.Client, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+			fmt.Sprintf("Expected *FlinkAppManagerProviderData, got: %T. Please report this issue to the provider developers.", req.ProviderData),
 		)
 
 		return
 	}
 
-	r.client = c
+	r.client = data.Client
+	r.provider = data
 }
 
 // Create 创建部署目标
@@ -96,22 +138,36 @@ func (r *DeploymentTargetResource) Create(ctx context.Context, req resource.Crea
 
 	// 创建部署目标
 	dt := &client.DeploymentTarget{
-		Metadata: &client.DeploymentTargetMetadata{Name: plan.Name.Value, Namespace: plan.Namespace.Value},
+		Metadata: &client.DeploymentTargetMetadata{
+			Name:        plan.Name.Value,
+			Namespace:   plan.Namespace.Value,
+			Labels:      mergeLabels(r.provider.DefaultLabels, plan.Labels),
+			Annotations: plan.Annotations,
+		},
 		Spec: &client.DeploymentTargetSpec{Kubernetes: &client.KubernetesTarget{
 			Namespace: k8sNamespace,
 		}},
 	}
-	deploymentTarget, _, err := r.client.CreateDeploymentTarget(dt, plan.Namespace.Value)
+	deploymentTarget, code, err := r.client.CreateDeploymentTarget(dt, plan.Namespace.Value)
+	// 开启adopt_existing时接管已存在且与配置一致的部署目标
+	if code == http.StatusConflict && plan.AdoptExisting.Value {
+		deploymentTarget, err = r.AdoptDeploymentTarget(dt, &plan)
+	}
 	if err != nil {
 		resp.Diagnostics.AddError("Error create deploymentTarget", "Could not create deploymentTarget, unexpected error: "+err.Error())
 		return
 	}
 
 	var result = DeploymentTargetResourceModel{
-		ID:           types.String{Value: deploymentTarget.Metadata.ID},
-		Namespace:    types.String{Value: deploymentTarget.Metadata.Namespace},
-		Name:         types.String{Value: deploymentTarget.Metadata.Name},
-		K8SNamespace: types.String{Value: deploymentTarget.Spec.Kubernetes.Namespace},
+		ID:             types.String{Value: deploymentTarget.Metadata.ID},
+		Namespace:      types.String{Value: deploymentTarget.Metadata.Namespace},
+		Name:           types.String{Value: deploymentTarget.Metadata.Name},
+		K8SNamespace:   types.String{Value: deploymentTarget.Spec.Kubernetes.Namespace},
+		Labels:         flattenLabels(deploymentTarget.Metadata.Labels, r.provider.DefaultLabels, plan.Labels, r.provider.IgnoreLabelPrefixes),
+		Annotations:    flattenAnnotations(deploymentTarget.Metadata.Annotations, plan.Annotations, r.provider.IgnoreAnnotationPrefixes),
+		AdoptExisting:  plan.AdoptExisting,
+		DeletionPolicy: plan.DeletionPolicy,
+		ForceDestroy:   plan.ForceDestroy,
 	}
 
 	// 保存状态
@@ -130,17 +186,32 @@ func (r *DeploymentTargetResource) Read(ctx context.Context, req resource.ReadRe
 		return
 	}
 
-	deploymentTarget, _, err := r.client.GetDeploymentTarget(state.Name.Value, state.Namespace.Value)
+	deploymentTarget, code, err := r.client.GetDeploymentTarget(state.Name.Value, state.Namespace.Value)
+	// 部署目标已被删除时从状态中移除
+	if code == http.StatusNotFound {
+		resp.State.RemoveResource(ctx)
+		return
+	}
 	if err != nil {
 		resp.Diagnostics.AddError("Error reading deploymentTarget", "Could not read deploymentTarget: "+err.Error())
 		return
 	}
+	// 部署目标在Terraform之外被重建
+	if r.provider.identityChanged("deploymentTarget", state.Namespace.Value+"/"+state.Name.Value, state.ID, deploymentTarget.Metadata.ID, &resp.Diagnostics) {
+		resp.State.RemoveResource(ctx)
+		return
+	}
 
 	var result = DeploymentTargetResourceModel{
-		ID:           types.String{Value: deploymentTarget.Metadata.ID},
-		Name:         types.String{Value: deploymentTarget.Metadata.Name},
-		Namespace:    types.String{Value: deploymentTarget.Metadata.Namespace},
-		K8SNamespace: types.String{Value: deploymentTarget.Spec.Kubernetes.Namespace},
+		ID:             types.String{Value: deploymentTarget.Metadata.ID},
+		Name:           types.String{Value: deploymentTarget.Metadata.Name},
+		Namespace:      types.String{Value: deploymentTarget.Metadata.Namespace},
+		K8SNamespace:   types.String{Value: deploymentTarget.Spec.Kubernetes.Namespace},
+		Labels:         flattenLabels(deploymentTarget.Metadata.Labels, r.provider.DefaultLabels, state.Labels, r.provider.IgnoreLabelPrefixes),
+		Annotations:    flattenAnnotations(deploymentTarget.Metadata.Annotations, state.Annotations, r.provider.IgnoreAnnotationPrefixes),
+		AdoptExisting:  state.AdoptExisting,
+		DeletionPolicy: state.DeletionPolicy,
+		ForceDestroy:   state.ForceDestroy,
 	}
 
 	// 部署目标写入状态
@@ -148,8 +219,18 @@ func (r *DeploymentTargetResource) Read(ctx context.Context, req resource.ReadRe
 	resp.Diagnostics.Append(resp.State.Set(ctx, result)...)
 }
 
+// Update 更新部署目标,AppManager不支持修改部署目标,变更会重建资源,此处仅保存计划值
 func (r *DeploymentTargetResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var plan DeploymentTargetResourceModel
+
+	// Read Terraform plan data into the model
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
 
+	// Save updated data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, plan)...)
 }
 
 // Delete 删除部署目标
@@ -163,8 +244,30 @@ func (r *DeploymentTargetResource) Delete(ctx context.Context, req resource.Dele
 		return
 	}
 
+	// 放弃管理时仅从状态中移除
+	if state.DeletionPolicy.Value == DeletionPolicyAbandon {
+		return
+	}
+
+	// 仍被作业或SessionCluster引用时拒绝删除
+	if !state.ForceDestroy.Value {
+		dependants, err := getDeploymentTargetDependants(r.client, state.Namespace.Value, state.ID.Value, state.Name.Value)
+		if err != nil {
+			resp.Diagnostics.AddError("Error delete deploymentTarget", "Could not list deploymentTarget dependants, unexpected error: "+err.Error())
+			return
+		}
+		if len(dependants) > 0 {
+			resp.Diagnostics.AddError("Error delete deploymentTarget", fmt.Sprintf("deploymentTarget %s/%s is %s", state.Namespace.Value, state.Name.Value, dependantsDetail(dependants)))
+			return
+		}
+	}
+
 	// 删除部署目标
-	_, _, err := r.client.DeleteDeploymentTarget(state.Name.Value, state.Namespace.Value)
+	_, code, err := r.client.DeleteDeploymentTarget(state.Name.Value, state.Namespace.Value)
+	// 部署目标已被删除时视为删除成功
+	if code == http.StatusNotFound {
+		return
+	}
 	if err != nil {
 		resp.Diagnostics.AddError("Error delete deploymentTarget", "Could not deleted deploymentTarget, unexpected error: "+err.Error())
 		return
@@ -186,3 +289,33 @@ func (r *DeploymentTargetResource) ImportState(ctx context.Context, req resource
 	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("namespace"), idParts[0])...)
 	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("name"), idParts[1])...)
 }
+
+// AdoptDeploymentTarget 接管已存在的部署目标,AppManager不支持修改部署目标,与配置不一致时返回差异
+func (r *DeploymentTargetResource) AdoptDeploymentTarget(desired *client.DeploymentTarget, plan *DeploymentTargetResourceModel) (*client.DeploymentTarget, error) {
+	existing, _, err := r.client.GetDeploymentTarget(desired.Metadata.Name, desired.Metadata.Namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	var diffs []string
+	if existing.Spec.Kubernetes.Namespace != desired.Spec.Kubernetes.Namespace {
+		diffs = append(diffs, fmt.Sprintf("  - k8s_namespace: existing %q, configured %q", existing.Spec.Kubernetes.Namespace, desired.Spec.Kubernetes.Namespace))
+	}
+
+	labels := flattenLabels(existing.Metadata.Labels, r.provider.DefaultLabels, plan.Labels, r.provider.IgnoreLabelPrefixes)
+	if !stringMapsEqual(labels, plan.Labels) {
+		diffs = append(diffs, fmt.Sprintf("  - labels: existing %v, configured %v", labels, plan.Labels))
+	}
+
+	annotations := flattenAnnotations(existing.Metadata.Annotations, plan.Annotations, r.provider.IgnoreAnnotationPrefixes)
+	if !stringMapsEqual(annotations, plan.Annotations) {
+		diffs = append(diffs, fmt.Sprintf("  - annotations: existing %v, configured %v", annotations, plan.Annotations))
+	}
+
+	if len(diffs) > 0 {
+		return nil, fmt.Errorf("deploymentTarget %s/%s already exists and differs from the configuration:\n%s",
+			desired.Metadata.Namespace, desired.Metadata.Name, strings.Join(diffs, "\n"))
+	}
+
+	return existing, nil
+}
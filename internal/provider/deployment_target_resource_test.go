@@ -19,6 +19,13 @@ func TestAccDeploymentTargetResource(t *testing.T) {
 					resource.ComposeTestCheckFunc(resource.TestCheckResourceAttr("flink_appmanager_deployment_target.test", "k8s_namespace", "default")),
 				),
 			},
+			// Changing k8s_namespace replaces the deployment target
+			{
+				Config: testAccDeploymentTargetResourceConfig("test", "flink"),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("flink_appmanager_deployment_target.test", "k8s_namespace", "flink"),
+				),
+			},
 			// Delete testing automatically occurs in TestCase
 		},
 	})
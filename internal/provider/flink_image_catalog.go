@@ -0,0 +1,137 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"git.sofunny.io/data-analysis-public/flink-appmanager-sdk/go/pkg/client"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+const (
+	// 推荐的最接近tag数量
+	ClosestFlinkImageTagCount = 3
+)
+
+// flinkImageCatalog AppManager ui/config.json 中的Flink镜像目录
+type flinkImageCatalog struct {
+	FlinkImageTagsAndRepository map[string]json.RawMessage `json:"flinkImageTagsAndRepository"`
+}
+
+// getFlinkImageTags 获取AppManager支持的Flink镜像tag
+func getFlinkImageTags(c *client.Client) ([]string, error) {
+	res, err := c.HttpClient.Get(fmt.Sprintf("%s/ui/config.json", c.Cfg.Endpoint))
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("get flink image catalog failed with status %d", res.StatusCode)
+	}
+
+	catalog := &flinkImageCatalog{}
+	if err = json.Unmarshal(body, catalog); err != nil {
+		return nil, fmt.Errorf("parse flink image catalog failed: %v", err)
+	}
+
+	tags := make([]string, 0, len(catalog.FlinkImageTagsAndRepository))
+	for tag := range catalog.FlinkImageTagsAndRepository {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	return tags, nil
+}
+
+// validateFlinkImageTag 在plan阶段校验flink_image_tag是否存在于镜像目录中
+func validateFlinkImageTag(ctx context.Context, c *client.Client, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	// 销毁资源、未发生变更或Provider未配置时无需校验
+	if req.Plan.Raw.IsNull() || req.Plan.Raw.Equal(req.State.Raw) || c == nil {
+		return
+	}
+
+	var tag types.String
+	resp.Diagnostics.Append(req.Plan.GetAttribute(ctx, path.Root("flink_image_tag"), &tag)...)
+	if resp.Diagnostics.HasError() || tag.Null || tag.Unknown {
+		return
+	}
+
+	tags, err := getFlinkImageTags(c)
+	if err != nil {
+		resp.Diagnostics.AddWarning("Unable to validate flink_image_tag", "Could not read the AppManager image catalog: "+err.Error())
+		return
+	}
+
+	if !containsString(tags, tag.Value) {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("flink_image_tag"),
+			"Invalid flink_image_tag",
+			fmt.Sprintf("Flink image tag %q is not available in the AppManager image catalog. Closest valid tags: %s",
+				tag.Value, strings.Join(closestStrings(tag.Value, tags, ClosestFlinkImageTagCount), ", ")),
+		)
+	}
+}
+
+// containsString 判断切片中是否包含指定字符串
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// closestStrings 按编辑距离返回最接近的n个候选值
+func closestStrings(value string, candidates []string, n int) []string {
+	sorted := make([]string, len(candidates))
+	copy(sorted, candidates)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return levenshtein(value, sorted[i]) < levenshtein(value, sorted[j])
+	})
+
+	if len(sorted) > n {
+		sorted = sorted[:n]
+	}
+	return sorted
+}
+
+// levenshtein 计算两个字符串的编辑距离
+func levenshtein(a string, b string) int {
+	x, y := []rune(a), []rune(b)
+	prev := make([]int, len(y)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(x); i++ {
+		cur := make([]int, len(y)+1)
+		cur[0] = i
+		for j := 1; j <= len(y); j++ {
+			cost := 1
+			if x[i-1] == y[j-1] {
+				cost = 0
+			}
+			cur[j] = minInt(minInt(prev[j]+1, cur[j-1]+1), prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(y)]
+}
+
+func minInt(a int, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,38 @@
+package provider
+
+import (
+	"git.sofunny.io/data-analysis-public/flink-appmanager-sdk/go/pkg/client"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestGetFlinkImageTags(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/ui/config.json" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(`{"flinkImageTagsAndRepository": {
+			"1.14.4-scala_2.12-java11-1": {"flinkVersion": "1.14", "image": {"repository": "registry/flink", "pullPolicy": "Always"}},
+			"1.13.6-scala_2.12-java8-1": {"flinkVersion": "1.13", "image": {"repository": "registry/flink", "pullPolicy": "Always"}}
+		}}`))
+	}))
+	defer server.Close()
+
+	tags, err := getFlinkImageTags(client.SetUp(client.Config{Endpoint: server.URL}))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	expected := []string{"1.13.6-scala_2.12-java8-1", "1.14.4-scala_2.12-java11-1"}
+	if !reflect.DeepEqual(tags, expected) {
+		t.Errorf("got tags %v, want %v", tags, expected)
+	}
+
+	closest := closestStrings("1.14.4-scala_2.12-java11", tags, 1)
+	if !reflect.DeepEqual(closest, []string{"1.14.4-scala_2.12-java11-1"}) {
+		t.Errorf("got closest tags %v", closest)
+	}
+}
@@ -0,0 +1,24 @@
+package provider
+
+import (
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// identityChanged 比较服务端对象ID与状态中的ID,判断对象是否在Terraform之外被删除重建。
+// 不一致时输出告警,开启strict_identity_check时返回true,由调用方将资源从状态中移除
+func (d *FlinkAppManagerProviderData) identityChanged(kind string, name string, stateID types.String, remoteID string, diags *diag.Diagnostics) bool {
+	if stateID.Null || stateID.Unknown || stateID.Value == "" || stateID.Value == remoteID {
+		return false
+	}
+
+	detail := fmt.Sprintf("%s %s has ID %q in AppManager but %q in state, it was probably deleted and recreated outside of Terraform.", kind, name, remoteID, stateID.Value)
+	if d.StrictIdentityCheck {
+		diags.AddWarning("Object recreated outside of Terraform", detail+" It has been removed from state and will be planned for creation.")
+		return true
+	}
+
+	diags.AddWarning("Object recreated outside of Terraform", detail+" Set strict_identity_check on the provider to remove such objects from state.")
+	return false
+}
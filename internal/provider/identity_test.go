@@ -0,0 +1,36 @@
+package provider
+
+import (
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"testing"
+)
+
+func TestIdentityChanged(t *testing.T) {
+	lenient := &FlinkAppManagerProviderData{}
+	strict := &FlinkAppManagerProviderData{StrictIdentityCheck: true}
+
+	// matching and freshly imported objects are not reported
+	for _, id := range []types.String{{Value: "a"}, {Null: true}} {
+		var diags diag.Diagnostics
+		if strict.identityChanged("namespace", "test", id, "a", &diags) || diags.WarningsCount() != 0 {
+			t.Errorf("unexpected identity change for state id %v: %v", id, diags)
+		}
+	}
+
+	var diags diag.Diagnostics
+	if lenient.identityChanged("namespace", "test", types.String{Value: "a"}, "b", &diags) {
+		t.Error("lenient check must keep the resource in state")
+	}
+	if diags.WarningsCount() != 1 {
+		t.Errorf("expected a warning, got %v", diags)
+	}
+
+	diags = nil
+	if !strict.identityChanged("namespace", "test", types.String{Value: "a"}, "b", &diags) {
+		t.Error("strict check must remove the resource from state")
+	}
+	if diags.WarningsCount() != 1 {
+		t.Errorf("expected a warning, got %v", diags)
+	}
+}
@@ -0,0 +1,113 @@
+package provider
+
+import "strings"
+
+// mergeLabels 合并Provider默认标签与资源标签,资源标签优先
+func mergeLabels(defaults map[string]string, labels map[string]string) map[string]string {
+	merged := make(map[string]string, len(defaults)+len(labels))
+	for k, v := range defaults {
+		merged[k] = v
+	}
+	for k, v := range labels {
+		merged[k] = v
+	}
+
+	if len(merged) == 0 {
+		return nil
+	}
+	return merged
+}
+
+// flattenLabels 移除服务端标签中来自Provider默认标签及需要忽略的部分,资源中声明过的标签保留。
+// 默认标签的key无论取值是否一致都会移除,修改default_labels不会在资源的labels上产生差异
+func flattenLabels(remote map[string]string, defaults map[string]string, configured map[string]string, ignorePrefixes []string) map[string]string {
+	labels := make(map[string]string, len(remote))
+	for k, v := range remote {
+		if _, ok := configured[k]; !ok {
+			if _, ok := defaults[k]; ok {
+				continue
+			}
+			if hasAnyPrefix(k, ignorePrefixes) {
+				continue
+			}
+		}
+		labels[k] = v
+	}
+	return emptyAsConfigured(labels, configured)
+}
+
+// withoutIgnoredKeys 移除需要忽略的key,结果为空时返回nil
+func withoutIgnoredKeys(m map[string]string, ignorePrefixes []string) map[string]string {
+	result := make(map[string]string, len(m))
+	for k, v := range m {
+		if !hasAnyPrefix(k, ignorePrefixes) {
+			result[k] = v
+		}
+	}
+
+	if len(result) == 0 {
+		return nil
+	}
+	return result
+}
+
+// flattenAnnotations 移除服务端注解中需要忽略的部分,资源中声明过的注解保留
+func flattenAnnotations(remote map[string]string, configured map[string]string, ignorePrefixes []string) map[string]string {
+	annotations := make(map[string]string, len(remote))
+	for k, v := range remote {
+		if _, ok := configured[k]; !ok && hasAnyPrefix(k, ignorePrefixes) {
+			continue
+		}
+		annotations[k] = v
+	}
+	return emptyAsConfigured(annotations, configured)
+}
+
+// preserveIgnoredKeys 将服务端上需要忽略的key合并到待提交的值中,避免覆盖时被删除
+func preserveIgnoredKeys(desired map[string]string, remote map[string]string, ignorePrefixes []string) map[string]string {
+	merged := make(map[string]string, len(desired))
+	for k, v := range remote {
+		if hasAnyPrefix(k, ignorePrefixes) {
+			merged[k] = v
+		}
+	}
+	for k, v := range desired {
+		merged[k] = v
+	}
+
+	if len(merged) == 0 {
+		return nil
+	}
+	return merged
+}
+
+// hasAnyPrefix 判断key是否以任一前缀开头
+func hasAnyPrefix(key string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// emptyAsConfigured 结果为空时与配置保持一致:未配置时为null,配置为空map时为空map
+func emptyAsConfigured(m map[string]string, configured map[string]string) map[string]string {
+	if len(m) == 0 && configured == nil {
+		return nil
+	}
+	return m
+}
+
+// stringMapsEqual 比较两个map是否相同,nil与空map视为相同
+func stringMapsEqual(a map[string]string, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if w, ok := b[k]; !ok || v != w {
+			return false
+		}
+	}
+	return true
+}
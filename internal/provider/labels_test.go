@@ -0,0 +1,70 @@
+package provider
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFlattenLabels(t *testing.T) {
+	defaults := map[string]string{"team": "data", "env": "prod"}
+	remote := map[string]string{"team": "data", "env": "staging", "app": "etl"}
+
+	// team and env come from the defaults and are not configured, whatever their value on the server
+	got := flattenLabels(remote, defaults, map[string]string{"app": "etl"}, nil)
+	expected := map[string]string{"app": "etl"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("got %v, want %v", got, expected)
+	}
+
+	// a configured label overriding a default is kept
+	got = flattenLabels(map[string]string{"team": "data"}, defaults, map[string]string{"team": "data"}, nil)
+	if !reflect.DeepEqual(got, map[string]string{"team": "data"}) {
+		t.Errorf("configured label removed: %v", got)
+	}
+
+	if got = flattenLabels(map[string]string{"team": "data"}, defaults, nil, nil); got != nil {
+		t.Errorf("expected null labels, got %v", got)
+	}
+}
+
+func TestWithoutIgnoredKeys(t *testing.T) {
+	prefixes := []string{"com.xmfunny.flink"}
+	got := withoutIgnoredKeys(map[string]string{"com.xmfunny.flink.deployment": "1", "team": "data"}, prefixes)
+	if !reflect.DeepEqual(got, map[string]string{"team": "data"}) {
+		t.Errorf("ignored label kept: %v", got)
+	}
+
+	if got = withoutIgnoredKeys(map[string]string{"com.xmfunny.flink.deployment": "1"}, prefixes); got != nil {
+		t.Errorf("expected nil labels, got %v", got)
+	}
+}
+
+func TestMergeLabels(t *testing.T) {
+	got := mergeLabels(map[string]string{"team": "data", "env": "prod"}, map[string]string{"env": "test"})
+	expected := map[string]string{"team": "data", "env": "test"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("got %v, want %v", got, expected)
+	}
+}
+
+func TestIgnoredAnnotations(t *testing.T) {
+	prefixes := []string{"com.xmfunny.flink"}
+	remote := map[string]string{
+		"com.xmfunny.flink.appmanager.controller.deployment.spec.version": "3",
+		"owner": "data",
+	}
+
+	got := flattenAnnotations(remote, map[string]string{"owner": "data"}, prefixes)
+	if !reflect.DeepEqual(got, map[string]string{"owner": "data"}) {
+		t.Errorf("controller annotation not ignored: %v", got)
+	}
+
+	merged := preserveIgnoredKeys(map[string]string{"owner": "ops"}, remote, prefixes)
+	expected := map[string]string{
+		"com.xmfunny.flink.appmanager.controller.deployment.spec.version": "3",
+		"owner": "ops",
+	}
+	if !reflect.DeepEqual(merged, expected) {
+		t.Errorf("got %v, want %v", merged, expected)
+	}
+}
@@ -11,27 +11,101 @@ type SessionClusterResourceModel struct {
 	DeploymentTargetName types.String             `tfsdk:"deployment_target_name"`
 	FlinkImageTag        types.String             `tfsdk:"flink_image_tag"`
 	NumberOfTaskManagers types.Int64              `tfsdk:"number_of_task_managers"`
-	Resources            map[string]*ResourceSpec `tfsdk:"resources"`
+	Resources            *SessionClusterResources `tfsdk:"resources"`
 	FlinkConfiguration   map[string]string        `tfsdk:"flink_configuration"`
+	Labels               map[string]string        `tfsdk:"labels"`
+	Annotations          map[string]string        `tfsdk:"annotations"`
+	EffectiveLabels      map[string]string        `tfsdk:"effective_labels"`
+	RestartPolicy        types.String             `tfsdk:"restart_policy"`
+	DeletionPolicy       types.String             `tfsdk:"deletion_policy"`
+	ForceDestroy         types.Bool               `tfsdk:"force_destroy"`
+	FlinkVersion         types.String             `tfsdk:"flink_version"`
+	FlinkImageRegistry   types.String             `tfsdk:"flink_image_registry"`
+	FlinkImageRepository types.String             `tfsdk:"flink_image_repository"`
+	StartedAt            types.String             `tfsdk:"started_at"`
+	LastUpdateTime       types.String             `tfsdk:"last_update_time"`
+	RunningTaskManagers  types.Int64              `tfsdk:"running_task_managers"`
+	Failure              *FailureModel            `tfsdk:"failure"`
+	CreatedAt            types.String             `tfsdk:"created_at"`
+	ModifiedAt           types.String             `tfsdk:"modified_at"`
+	ResourceVersion      types.Int64              `tfsdk:"resource_version"`
+}
+
+// FailureModel 失败信息Model
+type FailureModel struct {
+	Message  types.String `tfsdk:"message"`
+	Reason   types.String `tfsdk:"reason"`
+	FailedAt types.String `tfsdk:"failed_at"`
+}
+
+// SessionClusterResources 集群JobManager及TaskManager资源Model
+type SessionClusterResources struct {
+	JobManager  *ResourceSpec `tfsdk:"jobmanager"`
+	TaskManager *ResourceSpec `tfsdk:"taskmanager"`
 }
 
 // ResourceSpec 资源自定Model
 type ResourceSpec struct {
-	Cpu    types.Number `tfsdk:"cpu"`
-	Memory types.String `tfsdk:"memory"`
+	Cpu    types.Float64 `tfsdk:"cpu"`
+	Memory types.String  `tfsdk:"memory"`
 }
 
 // DeploymentTargetResourceModel 部署目标Model
 type DeploymentTargetResourceModel struct {
-	ID           types.String `tfsdk:"id"`
-	Namespace    types.String `tfsdk:"namespace"`
-	Name         types.String `tfsdk:"name"`
-	K8SNamespace types.String `tfsdk:"k8s_namespace"`
+	ID             types.String      `tfsdk:"id"`
+	Namespace      types.String      `tfsdk:"namespace"`
+	Name           types.String      `tfsdk:"name"`
+	K8SNamespace   types.String      `tfsdk:"k8s_namespace"`
+	Labels         map[string]string `tfsdk:"labels"`
+	Annotations    map[string]string `tfsdk:"annotations"`
+	AdoptExisting  types.Bool        `tfsdk:"adopt_existing"`
+	DeletionPolicy types.String      `tfsdk:"deletion_policy"`
+	ForceDestroy   types.Bool        `tfsdk:"force_destroy"`
 }
 
 // NamespaceResourceModel 部署空间Model
 type NamespaceResourceModel struct {
-	ID    types.String `tfsdk:"id"`
-	Name  types.String `tfsdk:"name"`
-	State types.String `tfsdk:"state"`
+	ID               types.String `tfsdk:"id"`
+	Name             types.String `tfsdk:"name"`
+	State            types.String `tfsdk:"state"`
+	AdoptExisting    types.Bool   `tfsdk:"adopt_existing"`
+	DeletionPolicy   types.String `tfsdk:"deletion_policy"`
+	ForceDestroy     types.Bool   `tfsdk:"force_destroy"`
+	DeleteSavepoints types.Bool   `tfsdk:"delete_savepoints"`
+}
+
+// BlueGreenDeploymentResourceModel BlueGreenDeploymentResource Model
+type BlueGreenDeploymentResourceModel struct {
+	ID                    types.String             `tfsdk:"id"`
+	Namespace             types.String             `tfsdk:"namespace"`
+	Name                  types.String             `tfsdk:"name"`
+	DeploymentTargetName  types.String             `tfsdk:"deployment_target_name"`
+	SessionClusterName    types.String             `tfsdk:"session_cluster_name"`
+	JarURI                types.String             `tfsdk:"jar_uri"`
+	EntryClass            types.String             `tfsdk:"entry_class"`
+	MainArgs              types.String             `tfsdk:"main_args"`
+	FlinkVersion          types.String             `tfsdk:"flink_version"`
+	FlinkImageTag         types.String             `tfsdk:"flink_image_tag"`
+	Parallelism           types.Int64              `tfsdk:"parallelism"`
+	Resources             *SessionClusterResources `tfsdk:"resources"`
+	FlinkConfiguration    map[string]string        `tfsdk:"flink_configuration"`
+	Labels                map[string]string        `tfsdk:"labels"`
+	AllowNonRestoredState types.Bool               `tfsdk:"allow_non_restored_state"`
+	AllowStatelessStart   types.Bool               `tfsdk:"allow_stateless_start"`
+	StabilizationSeconds  types.Int64              `tfsdk:"stabilization_seconds"`
+	MaxRestarts           types.Int64              `tfsdk:"max_restarts"`
+	DeletionPolicy        types.String             `tfsdk:"deletion_policy"`
+	ActiveColor           types.String             `tfsdk:"active_color"`
+	ActiveDeploymentName  types.String             `tfsdk:"active_deployment_name"`
+	RestoredSavepointID   types.String             `tfsdk:"restored_savepoint_id"`
+	LastSavepointLocation types.String             `tfsdk:"last_savepoint_location"`
+}
+
+// DeploymentStateResourceModel DeploymentStateResource Model
+type DeploymentStateResourceModel struct {
+	ID             types.String `tfsdk:"id"`
+	Namespace      types.String `tfsdk:"namespace"`
+	DeploymentName types.String `tfsdk:"deployment_name"`
+	State          types.String `tfsdk:"state"`
+	PreviousState  types.String `tfsdk:"previous_state"`
 }
@@ -0,0 +1,103 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"git.sofunny.io/data-analysis-public/flink-appmanager-sdk/go/pkg/client"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"net/http"
+)
+
+// emptyNamespace 清空部署空间中的作业、savepoint、SessionCluster、部署目标及资源文件,供force_destroy级联删除使用
+func emptyNamespace(ctx context.Context, c *client.Client, namespace string, deleteSavepoints bool) error {
+	// 取消并删除作业
+	deployments, code, err := c.GetDeployments(nil, namespace)
+	// 部署空间已被删除时无需清理
+	if code == http.StatusNotFound {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("list deployments failed: %v", err)
+	}
+	for _, d := range deployments {
+		name := d.Metadata.Name
+		if d.Status == nil || d.Status.State != client.DeploymentCancelled {
+			tflog.Info(ctx, "Cancelling deployment", map[string]interface{}{"namespace": namespace, "deployment": name})
+			if _, err = transitionDeployment(c, namespace, name, client.DeploymentCancelled); err != nil {
+				return fmt.Errorf("cancel deployment %s failed: %v", name, err)
+			}
+		}
+
+		tflog.Info(ctx, "Deleting deployment", map[string]interface{}{"namespace": namespace, "deployment": name})
+		_, code, err := c.DeleteDeployment(name, namespace)
+		if err != nil && code != http.StatusNotFound {
+			return fmt.Errorf("delete deployment %s failed: %v", name, err)
+		}
+	}
+
+	// 删除savepoint
+	if deleteSavepoints {
+		savepoints, _, err := c.GetSavepoints("", "", "", namespace)
+		if err != nil {
+			return fmt.Errorf("list savepoints failed: %v", err)
+		}
+		for _, s := range savepoints {
+			tflog.Info(ctx, "Deleting savepoint", map[string]interface{}{"namespace": namespace, "savepoint": s.Metadata.ID})
+			code, err := c.DeleteSavepoint(s.Metadata.ID, namespace, true)
+			if err != nil && code != http.StatusNotFound {
+				return fmt.Errorf("delete savepoint %s failed: %v", s.Metadata.ID, err)
+			}
+		}
+	}
+
+	// 停止并删除SessionCluster
+	sessionClusters, _, err := c.GetSessionClusters(namespace)
+	if err != nil {
+		return fmt.Errorf("list sessionClusters failed: %v", err)
+	}
+	scr := &SessionClusterResource{client: c}
+	for _, sc := range sessionClusters {
+		name := sc.Metadata.Name
+		tflog.Info(ctx, "Stopping sessionCluster", map[string]interface{}{"namespace": namespace, "session_cluster": name})
+		_, code, err := scr.StopSessionCluster(namespace, name, 0)
+		if err != nil && code != http.StatusNotFound {
+			return fmt.Errorf("stop sessionCluster %s failed: %v", name, err)
+		}
+
+		tflog.Info(ctx, "Deleting sessionCluster", map[string]interface{}{"namespace": namespace, "session_cluster": name})
+		_, code, err = c.DeleteSessionCluster(name, namespace)
+		if err != nil && code != http.StatusNotFound {
+			return fmt.Errorf("delete sessionCluster %s failed: %v", name, err)
+		}
+	}
+
+	// 删除部署目标
+	deploymentTargets, _, err := c.GetDeploymentTargets(namespace)
+	if err != nil {
+		return fmt.Errorf("list deploymentTargets failed: %v", err)
+	}
+	for _, dt := range deploymentTargets {
+		name := dt.Metadata.Name
+		tflog.Info(ctx, "Deleting deploymentTarget", map[string]interface{}{"namespace": namespace, "deployment_target": name})
+		_, code, err := c.DeleteDeploymentTarget(name, namespace)
+		if err != nil && code != http.StatusNotFound {
+			return fmt.Errorf("delete deploymentTarget %s failed: %v", name, err)
+		}
+	}
+
+	// 删除资源文件
+	artifacts, _, err := c.GetArtifacts(namespace)
+	if err != nil {
+		return fmt.Errorf("list artifacts failed: %v", err)
+	}
+	for _, a := range artifacts {
+		filename := a.Metadata.Filename
+		tflog.Info(ctx, "Deleting artifact", map[string]interface{}{"namespace": namespace, "artifact": filename})
+		_, code, err := c.DeleteArtifact(filename, namespace)
+		if err != nil && code != http.StatusNotFound {
+			return fmt.Errorf("delete artifact %s failed: %v", filename, err)
+		}
+	}
+
+	return nil
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/hashicorp/terraform-plugin-framework/resource"
 	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
 	"github.com/hashicorp/terraform-plugin-framework/types"
+	"net/http"
 )
 
 var _ resource.Resource = &NamespaceResource{}
@@ -20,7 +21,8 @@ func NewNamespaceResource() resource.Resource {
 
 // NamespaceResource defines the resource implementation.
 type NamespaceResource struct {
-	client *client.Client
+	client   *client.Client
+	provider *FlinkAppManagerProviderData
 }
 
 func (r *NamespaceResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
@@ -33,14 +35,40 @@ func (r *NamespaceResource) GetSchema(_ context.Context) (tfsdk.Schema, diag.Dia
 			"id": {
 				Type:     types.StringType,
 				Computed: true,
+				PlanModifiers: tfsdk.AttributePlanModifiers{
+					resource.UseStateForUnknown(),
+				},
 			},
 			"name": {
 				Type:     types.StringType,
 				Required: true,
+				PlanModifiers: tfsdk.AttributePlanModifiers{
+					resource.RequiresReplace(),
+				},
 			},
 			"state": {
 				Type:     types.StringType,
 				Computed: true,
+				PlanModifiers: tfsdk.AttributePlanModifiers{
+					resource.UseStateForUnknown(),
+				},
+			},
+			"adopt_existing": {
+				MarkdownDescription: "Take an existing namespace with the same name into state instead of failing on create.",
+				Type:                types.BoolType,
+				Optional:            true,
+			},
+			"deletion_policy": deletionPolicyAttribute(DeletionPolicyDelete, DeletionPolicyAbandon),
+			"force_destroy": {
+				MarkdownDescription: "Cancel and delete all deployments, stop and delete all session clusters and delete all deployment targets " +
+					"and artifacts of the namespace before deleting it.",
+				Type:     types.BoolType,
+				Optional: true,
+			},
+			"delete_savepoints": {
+				MarkdownDescription: "Also delete all savepoints of the namespace when `force_destroy` is set.",
+				Type:                types.BoolType,
+				Optional:            true,
 			},
 		},
 	}, nil
@@ -51,18 +79,19 @@ func (r *NamespaceResource) Configure(ctx context.Context, req resource.Configur
 	if req.ProviderData == nil {
 		return
 	}
-	c, ok := req.ProviderData.(*client.Client)
+	data, ok := req.ProviderData.(*FlinkAppManagerProviderData)
 
 	if !ok {
 		resp.Diagnostics.AddError(
 			"Unexpected Resource Configure Type",
-			fmt.Sprintf("Expected *http.Client, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+			fmt.Sprintf("Expected *FlinkAppManagerProviderData, got: %T. Please report this issue to the provider developers.", req.ProviderData),
 		)
 
 		return
 	}
 
-	r.client = c
+	r.client = data.Client
+	r.provider = data
 }
 
 // Create 创建部署空间
@@ -78,8 +107,9 @@ func (r *NamespaceResource) Create(ctx context.Context, req resource.CreateReque
 
 	// 创建部署空间
 	namespaceName := plan.Name.Value
-	_, _, err := r.client.CreateNamespace(namespaceName)
-	if err != nil {
+	_, code, err := r.client.CreateNamespace(namespaceName)
+	// 开启adopt_existing时接管已存在的部署空间
+	if err != nil && !(code == http.StatusConflict && plan.AdoptExisting.Value) {
 		resp.Diagnostics.AddError("Error creating namespace", "Could not create namespace, unexpected error: "+err.Error())
 		return
 	}
@@ -92,9 +122,13 @@ func (r *NamespaceResource) Create(ctx context.Context, req resource.CreateReque
 	}
 
 	var result = NamespaceResourceModel{
-		ID:    types.String{Value: namespaceState.Metadata.Id},
-		Name:  types.String{Value: namespaceState.Metadata.Name},
-		State: types.String{Value: namespaceState.Status.State},
+		ID:               types.String{Value: namespaceState.Metadata.Id},
+		Name:             types.String{Value: namespaceState.Metadata.Name},
+		State:            types.String{Value: namespaceState.Status.State},
+		AdoptExisting:    plan.AdoptExisting,
+		DeletionPolicy:   plan.DeletionPolicy,
+		ForceDestroy:     plan.ForceDestroy,
+		DeleteSavepoints: plan.DeleteSavepoints,
 	}
 
 	// 保存状态
@@ -114,16 +148,30 @@ func (r *NamespaceResource) Read(ctx context.Context, req resource.ReadRequest,
 	}
 
 	// 获取部署空间详情
-	namespace, _, err := r.client.GetNamespace(state.Name.Value)
+	namespace, code, err := r.client.GetNamespace(state.Name.Value)
+	// 部署空间已被删除时从状态中移除
+	if code == http.StatusNotFound {
+		resp.State.RemoveResource(ctx)
+		return
+	}
 	if err != nil {
 		resp.Diagnostics.AddError("Error reading namespace", "Could not read namespace: "+err.Error())
 		return
 	}
+	// 部署空间在Terraform之外被重建
+	if r.provider.identityChanged("namespace", state.Name.Value, state.ID, namespace.Metadata.Id, &resp.Diagnostics) {
+		resp.State.RemoveResource(ctx)
+		return
+	}
 
 	var result = NamespaceResourceModel{
-		ID:    types.String{Value: namespace.Metadata.Id},
-		Name:  types.String{Value: namespace.Metadata.Name},
-		State: types.String{Value: namespace.Status.State},
+		ID:               types.String{Value: namespace.Metadata.Id},
+		Name:             types.String{Value: namespace.Metadata.Name},
+		State:            types.String{Value: namespace.Status.State},
+		AdoptExisting:    state.AdoptExisting,
+		DeletionPolicy:   state.DeletionPolicy,
+		ForceDestroy:     state.ForceDestroy,
+		DeleteSavepoints: state.DeleteSavepoints,
 	}
 
 	// 部署空间写入状态
@@ -131,8 +179,18 @@ func (r *NamespaceResource) Read(ctx context.Context, req resource.ReadRequest,
 	resp.Diagnostics.Append(resp.State.Set(ctx, result)...)
 }
 
+// Update 更新部署空间,名称变更会重建资源,此处仅保存计划值
 func (r *NamespaceResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var plan NamespaceResourceModel
+
+	// Read Terraform plan data into the model
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
 
+	// Save updated data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, plan)...)
 }
 
 // Delete 删除部署空间
@@ -146,7 +204,21 @@ func (r *NamespaceResource) Delete(ctx context.Context, req resource.DeleteReque
 		return
 	}
 
-	// 删除部署空间
+	// 放弃管理时仅从状态中移除
+	if state.DeletionPolicy.Value == DeletionPolicyAbandon {
+		return
+	}
+
+	// 级联删除部署空间中的所有对象
+	if state.ForceDestroy.Value {
+		err := emptyNamespace(ctx, r.client, state.Name.Value, state.DeleteSavepoints.Value)
+		if err != nil {
+			resp.Diagnostics.AddError("Error Delete namespace", "Could not empty namespace, unexpected error: "+err.Error())
+			return
+		}
+	}
+
+	// 删除部署空间,部署空间不存在时视为删除成功
 	err := r.client.DeleteNamespaceCompleted(state.Name.Value)
 	if err != nil {
 		resp.Diagnostics.AddError("Error Delete namespace", "Could not delete namespace, unexpected error: "+err.Error())
@@ -32,9 +32,22 @@ type FlinkAppManagerProvider struct {
 
 // FlinkAppManagerProviderModel describes the provider data model.
 type FlinkAppManagerProviderModel struct {
-	Endpoint     types.String `tfsdk:"endpoint"`
-	WaitTimeout  types.Int64  `tfsdk:"wait_timeout"`
-	WaitInterval types.Int64  `tfsdk:"wait_interval"`
+	Endpoint                 types.String      `tfsdk:"endpoint"`
+	WaitTimeout              types.Int64       `tfsdk:"wait_timeout"`
+	WaitInterval             types.Int64       `tfsdk:"wait_interval"`
+	DefaultLabels            map[string]string `tfsdk:"default_labels"`
+	IgnoreLabelPrefixes      []string          `tfsdk:"ignore_label_prefixes"`
+	IgnoreAnnotationPrefixes []string          `tfsdk:"ignore_annotation_prefixes"`
+	StrictIdentityCheck      types.Bool        `tfsdk:"strict_identity_check"`
+}
+
+// FlinkAppManagerProviderData 传递给资源的Provider配置
+type FlinkAppManagerProviderData struct {
+	Client                   *client.Client
+	DefaultLabels            map[string]string
+	IgnoreLabelPrefixes      []string
+	IgnoreAnnotationPrefixes []string
+	StrictIdentityCheck      bool
 }
 
 func (p *FlinkAppManagerProvider) Metadata(_ context.Context, _ provider.MetadataRequest, resp *provider.MetadataResponse) {
@@ -58,6 +71,30 @@ func (p *FlinkAppManagerProvider) GetSchema(_ context.Context) (tfsdk.Schema, di
 				Type:     types.Int64Type,
 				Optional: true,
 			},
+			"default_labels": {
+				MarkdownDescription: "Labels added to every object managed by the provider that supports labels. Labels set on a resource take precedence.",
+				Type:                types.MapType{ElemType: types.StringType},
+				Optional:            true,
+			},
+			"ignore_label_prefixes": {
+				MarkdownDescription: "Label key prefixes managed outside of Terraform, such as by the AppManager controller. " +
+					"Matching labels are not tracked in state and are kept on the server on update. Defaults to `" + client.AnnotationPrefix + "`.",
+				Type:     types.ListType{ElemType: types.StringType},
+				Optional: true,
+			},
+			"ignore_annotation_prefixes": {
+				MarkdownDescription: "Annotation key prefixes managed outside of Terraform, such as by the AppManager controller. " +
+					"Matching annotations are not tracked in state and are kept on the server on update. Defaults to `" + client.AnnotationPrefix + "`.",
+				Type:     types.ListType{ElemType: types.StringType},
+				Optional: true,
+			},
+			"strict_identity_check": {
+				MarkdownDescription: "Remove objects from state when their server-side ID no longer matches the stored `id`, " +
+					"so that objects deleted and recreated outside of Terraform show up as a replacement in the plan. " +
+					"By default only a warning is emitted.",
+				Type:     types.BoolType,
+				Optional: true,
+			},
 		},
 	}, nil
 }
@@ -105,12 +142,33 @@ func (p *FlinkAppManagerProvider) Configure(ctx context.Context, req provider.Co
 		Timeout:  time.Duration(waitTimeout) * time.Second,
 	})
 
-	resp.DataSourceData = c
-	resp.ResourceData = c
+	// 默认忽略AppManager控制器写入的标签及注解
+	ignoreLabelPrefixes := config.IgnoreLabelPrefixes
+	if ignoreLabelPrefixes == nil {
+		ignoreLabelPrefixes = []string{client.AnnotationPrefix}
+	}
+
+	ignoreAnnotationPrefixes := config.IgnoreAnnotationPrefixes
+	if ignoreAnnotationPrefixes == nil {
+		ignoreAnnotationPrefixes = []string{client.AnnotationPrefix}
+	}
+
+	data := &FlinkAppManagerProviderData{
+		Client:                   c,
+		DefaultLabels:            config.DefaultLabels,
+		IgnoreLabelPrefixes:      ignoreLabelPrefixes,
+		IgnoreAnnotationPrefixes: ignoreAnnotationPrefixes,
+		StrictIdentityCheck:      config.StrictIdentityCheck.Value,
+	}
+
+	resp.DataSourceData = data
+	resp.ResourceData = data
 }
 
 func (p *FlinkAppManagerProvider) Resources(_ context.Context) []func() resource.Resource {
 	return []func() resource.Resource{
+		NewBlueGreenDeploymentResource,
+		NewDeploymentStateResource,
 		NewDeploymentTargetResource,
 		NewNamespaceResource,
 		NewSessionClusterResource,
@@ -0,0 +1,77 @@
+package provider
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// 内存单位,与Flink一致按1024进位,同时兼容Kubernetes的Ki/Mi/Gi写法
+var memoryUnits = map[string]float64{
+	"":    1,
+	"b":   1,
+	"k":   1 << 10,
+	"kb":  1 << 10,
+	"ki":  1 << 10,
+	"kib": 1 << 10,
+	"m":   1 << 20,
+	"mb":  1 << 20,
+	"mi":  1 << 20,
+	"mib": 1 << 20,
+	"g":   1 << 30,
+	"gb":  1 << 30,
+	"gi":  1 << 30,
+	"gib": 1 << 30,
+	"t":   1 << 40,
+	"tb":  1 << 40,
+	"ti":  1 << 40,
+	"tib": 1 << 40,
+}
+
+// parseMemory 解析内存大小,返回字节数,如 1G、1Gi、1024m、1024Mi
+func parseMemory(s string) (float64, error) {
+	value := strings.TrimSpace(s)
+	i := strings.IndexFunc(value, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+	if i < 0 {
+		i = len(value)
+	}
+
+	number, unit := value[:i], strings.ToLower(strings.TrimSpace(value[i:]))
+	n, err := strconv.ParseFloat(number, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid memory quantity %q, expected a positive number with an optional unit such as 1G, 1Gi or 1024m", s)
+	}
+
+	multiplier, ok := memoryUnits[unit]
+	if !ok {
+		return 0, fmt.Errorf("invalid memory unit %q in %q, expected one of k, m, g, t with an optional i or b suffix", unit, s)
+	}
+
+	return n * multiplier, nil
+}
+
+// memoryEqual 判断两个内存大小是否等价,无法解析时按字符串比较
+func memoryEqual(a string, b string) bool {
+	x, err := parseMemory(a)
+	if err != nil {
+		return a == b
+	}
+	y, err := parseMemory(b)
+	if err != nil {
+		return a == b
+	}
+	return x == y
+}
+
+// cpuEqual 判断两个CPU数量是否等价,精度为毫核
+func cpuEqual(a float64, b float64) bool {
+	return math.Round(a*1000) == math.Round(b*1000)
+}
+
+// validCpu 判断CPU数量大于0且精度不超过毫核
+func validCpu(v float64) bool {
+	return v > 0 && math.Abs(v*1000-math.Round(v*1000)) <= 1e-9
+}
@@ -0,0 +1,54 @@
+package provider
+
+import "testing"
+
+func TestMemoryEqual(t *testing.T) {
+	cases := []struct {
+		a, b  string
+		equal bool
+	}{
+		{"1024m", "1G", true},
+		{"1Gi", "1024Mi", true},
+		{"1g", "1G", true},
+		{"1.5G", "1536m", true},
+		{"2048k", "2m", true},
+		{"1G", "1000m", false},
+		{"1G", "2G", false},
+	}
+
+	for _, c := range cases {
+		if got := memoryEqual(c.a, c.b); got != c.equal {
+			t.Errorf("memoryEqual(%q, %q) = %t, want %t", c.a, c.b, got, c.equal)
+		}
+	}
+}
+
+func TestParseMemoryInvalid(t *testing.T) {
+	for _, s := range []string{"", "G", "-1G", "1X", "1 GiBs", "abc"} {
+		if _, err := parseMemory(s); err == nil {
+			t.Errorf("parseMemory(%q) expected an error", s)
+		}
+	}
+}
+
+func TestCpuEqual(t *testing.T) {
+	if !cpuEqual(0.5, 0.5000001) {
+		t.Error("expected 0.5 and 0.5000001 to be equal at millicore precision")
+	}
+	if cpuEqual(0.5, 0.501) {
+		t.Error("expected 0.5 and 0.501 to differ")
+	}
+}
+
+func TestValidCpu(t *testing.T) {
+	for _, v := range []float64{0.5, 0.001, 1, 2.25} {
+		if !validCpu(v) {
+			t.Errorf("validCpu(%g) = false, want true", v)
+		}
+	}
+	for _, v := range []float64{0, -1, 0.0004, 0.0005, 1.0001} {
+		if validCpu(v) {
+			t.Errorf("validCpu(%g) = true, want false", v)
+		}
+	}
+}
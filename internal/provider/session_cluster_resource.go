@@ -2,20 +2,42 @@ package provider
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"git.sofunny.io/data-analysis-public/flink-appmanager-sdk/go/pkg/client"
-	"github.com/hashicorp/terraform-plugin-framework/attr"
 	"github.com/hashicorp/terraform-plugin-framework/diag"
 	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
 	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
 	"github.com/hashicorp/terraform-plugin-framework/types"
-	"math/big"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+	"net/http"
+	"reflect"
 	"strings"
+	"time"
 )
 
 var _ resource.Resource = &SessionClusterResource{}
 var _ resource.ResourceWithImportState = &SessionClusterResource{}
+var _ resource.ResourceWithUpgradeState = &SessionClusterResource{}
+var _ resource.ResourceWithModifyPlan = &SessionClusterResource{}
+
+const (
+	// SessionClusterRestartPolicyStop 直接停止集群,运行中的作业会随集群停止
+	SessionClusterRestartPolicyStop = "stop"
+	// SessionClusterRestartPolicySuspendDeployments 先暂停作业(触发savepoint),集群重启后再恢复作业
+	SessionClusterRestartPolicySuspendDeployments = "suspend_deployments"
+)
+
+const (
+	ResourceJobManager  = "jobmanager"
+	ResourceTaskManager = "taskmanager"
+)
+
+const (
+	// ResourceVersionConflictRetries resourceVersion冲突时最多尝试的次数
+	ResourceVersionConflictRetries = 3
+)
 
 func NewSessionClusterResource() resource.Resource {
 	return &SessionClusterResource{}
@@ -23,7 +45,8 @@ func NewSessionClusterResource() resource.Resource {
 
 // SessionClusterResource defines the resource implementation.
 type SessionClusterResource struct {
-	client *client.Client
+	client   *client.Client
+	provider *FlinkAppManagerProviderData
 }
 
 func (r *SessionClusterResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
@@ -32,10 +55,14 @@ func (r *SessionClusterResource) Metadata(ctx context.Context, req resource.Meta
 
 func (r *SessionClusterResource) GetSchema(_ context.Context) (tfsdk.Schema, diag.Diagnostics) {
 	return tfsdk.Schema{
+		Version: 1,
 		Attributes: map[string]tfsdk.Attribute{
 			"id": {
 				Type:     types.StringType,
 				Computed: true,
+				PlanModifiers: tfsdk.AttributePlanModifiers{
+					resource.UseStateForUnknown(),
+				},
 			},
 			"state": {
 				Type:     types.StringType,
@@ -44,10 +71,16 @@ func (r *SessionClusterResource) GetSchema(_ context.Context) (tfsdk.Schema, dia
 			"namespace": {
 				Type:     types.StringType,
 				Required: true,
+				PlanModifiers: tfsdk.AttributePlanModifiers{
+					resource.RequiresReplace(),
+				},
 			},
 			"name": {
 				Type:     types.StringType,
 				Required: true,
+				PlanModifiers: tfsdk.AttributePlanModifiers{
+					resource.RequiresReplace(),
+				},
 			},
 			"deployment_target_name": {
 				Type:     types.StringType,
@@ -62,37 +95,165 @@ func (r *SessionClusterResource) GetSchema(_ context.Context) (tfsdk.Schema, dia
 				Required: true,
 			},
 			"resources": {
-				Type: types.MapType{ElemType: types.ObjectType{AttrTypes: map[string]attr.Type{
-					"cpu":    types.NumberType,
-					"memory": types.StringType,
-				}}},
 				Required: true,
+				Attributes: tfsdk.SingleNestedAttributes(map[string]tfsdk.Attribute{
+					ResourceJobManager:  resourceSpecAttribute("Resources of the job manager."),
+					ResourceTaskManager: resourceSpecAttribute("Resources of each task manager."),
+				}),
 			},
 			"flink_configuration": {
 				Type:     types.MapType{ElemType: types.StringType},
 				Required: true,
 			},
+			"labels": {
+				MarkdownDescription: "Labels of the session cluster. Changing labels does not restart the cluster.",
+				Type:                types.MapType{ElemType: types.StringType},
+				Optional:            true,
+			},
+			"annotations": {
+				MarkdownDescription: "Annotations of the session cluster. Changing annotations does not restart the cluster.",
+				Type:                types.MapType{ElemType: types.StringType},
+				Optional:            true,
+			},
+			"effective_labels": {
+				MarkdownDescription: "Labels of the session cluster in AppManager including the provider `default_labels`, " +
+					"so that changing `default_labels` updates existing clusters without restarting them. " +
+					"Labels matching `ignore_label_prefixes` are not tracked.",
+				Type:     types.MapType{ElemType: types.StringType},
+				Computed: true,
+			},
+			"restart_policy": {
+				MarkdownDescription: "How deployments running on the cluster are handled when a change requires a restart. " +
+					"`stop` (default) stops the cluster together with its jobs, `suspend_deployments` suspends the running " +
+					"deployments with a savepoint first and resumes them once the cluster is running again.",
+				Type:     types.StringType,
+				Optional: true,
+				Validators: []tfsdk.AttributeValidator{
+					stringOneOf(SessionClusterRestartPolicyStop, SessionClusterRestartPolicySuspendDeployments),
+				},
+			},
+			"deletion_policy": sessionClusterDeletionPolicyAttribute(),
+			"force_destroy": {
+				MarkdownDescription: "Stop and delete the session cluster even if deployments still run on it.",
+				Type:                types.BoolType,
+				Optional:            true,
+			},
+			"flink_version": {
+				MarkdownDescription: "Flink version resolved from `flink_image_tag`.",
+				Type:                types.StringType,
+				Computed:            true,
+			},
+			"flink_image_registry": {
+				MarkdownDescription: "Image registry resolved from `flink_image_tag`.",
+				Type:                types.StringType,
+				Computed:            true,
+			},
+			"flink_image_repository": {
+				MarkdownDescription: "Image repository resolved from `flink_image_tag`.",
+				Type:                types.StringType,
+				Computed:            true,
+			},
+			"started_at": {
+				MarkdownDescription: "Time the cluster was last started.",
+				Type:                types.StringType,
+				Computed:            true,
+			},
+			"last_update_time": {
+				MarkdownDescription: "Time the running status was last updated.",
+				Type:                types.StringType,
+				Computed:            true,
+			},
+			"running_task_managers": {
+				MarkdownDescription: "Number of task managers observed by AppManager.",
+				Type:                types.Int64Type,
+				Computed:            true,
+			},
+			"failure": {
+				MarkdownDescription: "Last failure reported for the cluster, if any.",
+				Computed:            true,
+				Attributes: tfsdk.SingleNestedAttributes(map[string]tfsdk.Attribute{
+					"message": {
+						Type:     types.StringType,
+						Computed: true,
+					},
+					"reason": {
+						Type:     types.StringType,
+						Computed: true,
+					},
+					"failed_at": {
+						Type:     types.StringType,
+						Computed: true,
+					},
+				}),
+			},
+			"created_at": {
+				Type:     types.StringType,
+				Computed: true,
+				PlanModifiers: tfsdk.AttributePlanModifiers{
+					resource.UseStateForUnknown(),
+				},
+			},
+			"modified_at": {
+				Type:     types.StringType,
+				Computed: true,
+			},
+			"resource_version": {
+				MarkdownDescription: "Version of the session cluster in AppManager. It is sent on updates so that changes made " +
+					"outside of Terraform since the last refresh are detected instead of overwritten.",
+				Type:     types.Int64Type,
+				Computed: true,
+			},
 		},
 	}, nil
 }
 
+// sessionClusterDeletionPolicyAttribute SessionCluster额外支持仅停止集群
+func sessionClusterDeletionPolicyAttribute() tfsdk.Attribute {
+	attr := deletionPolicyAttribute(DeletionPolicyDelete, DeletionPolicyStop, DeletionPolicyAbandon)
+	attr.MarkdownDescription += " `STOP` stops the cluster but keeps its definition in AppManager."
+	return attr
+}
+
+// 资源配置属性定义
+func resourceSpecAttribute(description string) tfsdk.Attribute {
+	return tfsdk.Attribute{
+		MarkdownDescription: description,
+		Optional:            true,
+		Attributes: tfsdk.SingleNestedAttributes(map[string]tfsdk.Attribute{
+			"cpu": {
+				MarkdownDescription: "Number of CPU cores, compared at millicore precision.",
+				Type:                types.Float64Type,
+				Required:            true,
+				Validators:          []tfsdk.AttributeValidator{cpuQuantity()},
+			},
+			"memory": {
+				MarkdownDescription: "Memory size such as `1G`, `1Gi` or `1024m`. Units are 1024 based, switching to an equivalent notation only updates the state.",
+				Type:                types.StringType,
+				Required:            true,
+				Validators:          []tfsdk.AttributeValidator{memoryQuantity()},
+			},
+		}),
+	}
+}
+
 func (r *SessionClusterResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
 	// Prevent panic if the provider has not been configured.
 	if req.ProviderData == nil {
 		return
 	}
-	c, ok := req.ProviderData.(*client.Client)
+	data, ok := req.ProviderData.(*FlinkAppManagerProviderData)
 
 	if !ok {
 		resp.Diagnostics.AddError(
 			"Unexpected Resource Configure Type",
-			fmt.Sprintf("Expected *http.Client, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+			fmt.Sprintf("Expected *FlinkAppManagerProviderData, got: %T. Please report this issue to the provider developers.", req.ProviderData),
 		)
 
 		return
 	}
 
-	r.client = c
+	r.client = data.Client
+	r.provider = data
 }
 
 // Create 创建运行集群
@@ -107,13 +268,14 @@ func (r *SessionClusterResource) Create(ctx context.Context, req resource.Create
 	}
 
 	// 创建SessionCluster集群
-	sc, err := r.RunSessionCluster(plan.Namespace.Value, buildSessionClusterDTO(&plan))
+	sc, _, err := r.RunSessionCluster(plan.Namespace.Value, r.buildSessionClusterDTO(&plan))
 	if err != nil {
 		resp.Diagnostics.AddError("Error create sessionCluster", "could not create sessionCluster, unexpected error: "+err.Error())
+		return
 	}
 
 	// 根据SessionCluster集群信息构建tf值
-	var result = buildSessionClusterTfValue(sc)
+	var result = r.buildSessionClusterState(sc, &plan)
 	// 写出集群状态
 	// Save data into Terraform state
 	resp.Diagnostics.Append(resp.State.Set(ctx, result)...)
@@ -131,20 +293,73 @@ func (r *SessionClusterResource) Read(ctx context.Context, req resource.ReadRequ
 	}
 
 	// 查询SessionCluster集群信息
-	sessionCluster, _, err := r.client.GetSessionCluster(state.Name.Value, state.Namespace.Value)
+	sessionCluster, code, err := r.client.GetSessionCluster(state.Name.Value, state.Namespace.Value)
+	// 集群已被删除时从状态中移除
+	if code == http.StatusNotFound {
+		resp.State.RemoveResource(ctx)
+		return
+	}
 	if err != nil {
 		resp.Diagnostics.AddError("Error reading sessionCluster", "Could not read sessionCluster, unexpected error:: "+err.Error())
 		return
 	}
+	// 集群在Terraform之外被重建
+	if r.provider.identityChanged("sessionCluster", state.Namespace.Value+"/"+state.Name.Value, state.ID, sessionCluster.Metadata.Id, &resp.Diagnostics) {
+		resp.State.RemoveResource(ctx)
+		return
+	}
 
 	// 根据SessionCluster集群信息构建tf值
-	var result = buildSessionClusterTfValue(sessionCluster)
+	var result = r.buildSessionClusterState(sessionCluster, &state)
+
+	// 集群使用的镜像已从AppManager镜像目录中移除时提示
+	if tags, err := getFlinkImageTags(r.client); err == nil && !containsString(tags, result.FlinkImageTag.Value) {
+		resp.Diagnostics.AddWarning(
+			"Flink image tag no longer available",
+			fmt.Sprintf("SessionCluster %s/%s runs flink image tag %q which has been removed from the AppManager image catalog. "+
+				"The next change to this cluster will fail until a valid flink_image_tag is configured.",
+				result.Namespace.Value, result.Name.Value, result.FlinkImageTag.Value),
+		)
+	}
+
+	// 运行中的TaskManager数量少于期望数量时提示
+	if result.State.Value == client.ClusterRunning && result.RunningTaskManagers.Value < result.NumberOfTaskManagers.Value {
+		resp.Diagnostics.AddWarning(
+			"SessionCluster is under-provisioned",
+			fmt.Sprintf("SessionCluster %s/%s is running %d of %d task managers.",
+				result.Namespace.Value, result.Name.Value, result.RunningTaskManagers.Value, result.NumberOfTaskManagers.Value),
+		)
+	}
 
 	// 写出集群状态
 	// Save updated data into Terraform state
 	resp.Diagnostics.Append(resp.State.Set(ctx, result)...)
 }
 
+// ModifyPlan 在计划阶段校验镜像tag,避免apply过程中停止集群后才发现tag无效,并计划合并默认标签后的集群标签
+func (r *SessionClusterResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	validateFlinkImageTag(ctx, r.client, req, resp)
+	if req.Plan.Raw.IsNull() || r.provider == nil {
+		return
+	}
+
+	// 标签在计划阶段未知时保持effective_labels未知
+	var labels types.Map
+	resp.Diagnostics.Append(req.Plan.GetAttribute(ctx, path.Root("labels"), &labels)...)
+	if resp.Diagnostics.HasError() || labels.Unknown {
+		return
+	}
+	configured := make(map[string]string, len(labels.Elems))
+	for k, v := range labels.Elems {
+		value, ok := v.(types.String)
+		if !ok || value.Unknown {
+			return
+		}
+		configured[k] = value.Value
+	}
+	resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("effective_labels"), r.effectiveLabels(configured))...)
+}
+
 // Update 更新集群
 func (r *SessionClusterResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
 	// 获取状态参数
@@ -156,13 +371,6 @@ func (r *SessionClusterResource) Update(ctx context.Context, req resource.Update
 		return
 	}
 
-	name := state.Name.Value
-	_, err := r.StopSessionCluster(name, state.Namespace.Value)
-	if err != nil {
-		resp.Diagnostics.AddError("Error stop sessionCluster", "Could stop sessionCluster, unexpected error: "+err.Error())
-		return
-	}
-
 	// 读取配置
 	var plan SessionClusterResourceModel
 	planDiags := req.Config.Get(ctx, &plan)
@@ -171,14 +379,84 @@ func (r *SessionClusterResource) Update(ctx context.Context, req resource.Update
 		return
 	}
 
-	// 创建SessionCluster集群
-	sc, err := r.RunSessionCluster(plan.Namespace.Value, buildSessionClusterDTO(&plan))
+	// 集群配置未变更时无需重启集群
+	if !sessionClusterSpecChanged(&state, &plan) {
+		state.RestartPolicy = plan.RestartPolicy
+		state.DeletionPolicy = plan.DeletionPolicy
+		state.ForceDestroy = plan.ForceDestroy
+		state.Resources = plan.Resources
+
+		// 仅标签、注解或Provider默认标签变更时直接更新集群元数据
+		if sessionClusterMetadataChanged(&state, &plan) || !stringMapsEqual(state.EffectiveLabels, r.effectiveLabels(plan.Labels)) {
+			sc, code, err := r.UpdateSessionClusterMetadata(&state, &plan)
+			if err != nil {
+				resp.Diagnostics.AddError("Error update sessionCluster", "Could not update sessionCluster metadata, unexpected error: "+err.Error()+resourceVersionConflictDetail("sessionCluster", code))
+				return
+			}
+			state = *r.buildSessionClusterState(sc, &plan)
+		}
+		// 标签和注解始终以计划为准,避免 null 与空 map 不一致
+		state.Labels = plan.Labels
+		state.Annotations = plan.Annotations
+
+		resp.Diagnostics.Append(resp.State.Set(ctx, state)...)
+		return
+	}
+
+	namespace := state.Namespace.Value
+	name := state.Name.Value
+
+	// 停止集群前先暂停运行在集群上的作业
+	var suspended *deploymentStates
+	if plan.RestartPolicy.Value == SessionClusterRestartPolicySuspendDeployments {
+		var err error
+		suspended, err = r.SuspendSessionClusterDeployments(namespace, name)
+		if err != nil {
+			// 集群尚未停止,尽量恢复已暂停的作业
+			_ = r.ResumeDeployments(namespace, suspended)
+			resp.Diagnostics.AddError("Error suspend deployments", "Could not suspend deployments running on sessionCluster, unexpected error: "+err.Error()+"\n\n"+suspended.String())
+			return
+		}
+	}
+
+	// 携带状态中的resourceVersion停止集群,避免覆盖Terraform之外的修改
+	stopped, code, err := r.updateSessionClusterWithRetry(&state, int(state.ResourceVersion.Value), func(resourceVersion int) (*client.SessionCluster, int, error) {
+		return r.StopSessionCluster(namespace, name, resourceVersion)
+	})
 	if err != nil {
-		resp.Diagnostics.AddError("Error create sessionCluster", "could not create sessionCluster, unexpected error: "+err.Error())
+		detail := resourceVersionConflictDetail("sessionCluster", code)
+		// 集群未能停止,尽量恢复已暂停的作业并输出各作业最终状态
+		if suspended != nil {
+			_ = r.ResumeDeployments(namespace, suspended)
+			detail += "\n\n" + suspended.String()
+		}
+		resp.Diagnostics.AddError("Error stop sessionCluster", "Could not stop sessionCluster, unexpected error: "+err.Error()+detail)
+		return
+	}
+
+	// 创建SessionCluster集群,保留服务端上需要忽略的标签及注解
+	dto := r.buildSessionClusterDTO(&plan)
+	dto.Metadata.Labels = preserveIgnoredKeys(dto.Metadata.Labels, stopped.Metadata.Labels, r.provider.IgnoreLabelPrefixes)
+	dto.Metadata.Annotations = preserveIgnoredKeys(dto.Metadata.Annotations, stopped.Metadata.Annotations, r.provider.IgnoreAnnotationPrefixes)
+	sc, code, err := r.updateSessionClusterWithRetry(r.buildSessionClusterState(stopped, &state), stopped.Metadata.ResourceVersion, func(resourceVersion int) (*client.SessionCluster, int, error) {
+		dto.Metadata.ResourceVersion = resourceVersion
+		return r.RunSessionCluster(plan.Namespace.Value, dto)
+	})
+	if err != nil {
+		resp.Diagnostics.AddError("Error create sessionCluster", "could not create sessionCluster, unexpected error: "+err.Error()+resourceVersionConflictDetail("sessionCluster", code)+deploymentStatesDetail(suspended))
+		return
+	}
+
+	// 集群启动后恢复之前暂停的作业
+	if suspended != nil {
+		err = r.ResumeDeployments(namespace, suspended)
+		if err != nil {
+			resp.Diagnostics.AddError("Error resume deployments", "Could not resume deployments after sessionCluster restart, unexpected error: "+err.Error()+"\n\n"+suspended.String())
+		}
 	}
 
 	// 根据SessionCluster集群信息构建tf值
-	var result = buildSessionClusterTfValue(sc)
+	var result = r.buildSessionClusterState(sc, &plan)
 	// 写出集群状态
 	// Save updated data into Terraform state
 	resp.Diagnostics.Append(resp.State.Set(ctx, result)...)
@@ -194,17 +472,45 @@ func (r *SessionClusterResource) Delete(ctx context.Context, req resource.Delete
 		return
 	}
 
+	// 放弃管理时仅从状态中移除
+	if state.DeletionPolicy.Value == DeletionPolicyAbandon {
+		return
+	}
+
 	sessionClusterName := state.Name.Value
-	// 停止SessionCluster
-	_, err := r.StopSessionCluster(state.Namespace.Value, sessionClusterName)
+	// 仍有作业运行在集群上时拒绝删除
+	if !state.ForceDestroy.Value {
+		dependants, err := getSessionClusterDependants(r.client, state.Namespace.Value, sessionClusterName)
+		if err != nil {
+			resp.Diagnostics.AddError("Error delete sessionCluster", "Could not list sessionCluster dependants, unexpected error: "+err.Error())
+			return
+		}
+		if len(dependants) > 0 {
+			resp.Diagnostics.AddError("Error delete sessionCluster", fmt.Sprintf("sessionCluster %s/%s is %s", state.Namespace.Value, sessionClusterName, dependantsDetail(dependants)))
+			return
+		}
+	}
+
+	// 停止SessionCluster,集群已被删除时视为删除成功
+	_, code, err := r.StopSessionCluster(state.Namespace.Value, sessionClusterName, 0)
+	if code == http.StatusNotFound {
+		return
+	}
 	if err != nil {
 		resp.Diagnostics.AddError("Error stop sessionCluster", "Could not stop sessionCluster, unexpected error: "+err.Error())
 		return
 	}
 
-	// 删除集群名称
-	_, _, err = r.client.DeleteSessionCluster(sessionClusterName, state.Namespace.Value)
+	// 仅停止时保留集群定义
+	if state.DeletionPolicy.Value == DeletionPolicyStop {
+		return
+	}
 
+	// 删除集群名称
+	_, code, err = r.client.DeleteSessionCluster(sessionClusterName, state.Namespace.Value)
+	if code == http.StatusNotFound {
+		return
+	}
 	if err != nil {
 		resp.Diagnostics.AddError("Error delete sessionCluster", "Could not delete sessionCluster, unexpected error: "+err.Error())
 		return
@@ -227,17 +533,97 @@ func (r *SessionClusterResource) ImportState(ctx context.Context, req resource.I
 	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("name"), idParts[1])...)
 }
 
+// UpgradeState 升级旧版本状态
+func (r *SessionClusterResource) UpgradeState(ctx context.Context) map[int64]resource.StateUpgrader {
+	return map[int64]resource.StateUpgrader{
+		// 版本0中resources为以组件名为key的map,版本1拆分为jobmanager及taskmanager属性
+		0: {
+			StateUpgrader: upgradeSessionClusterStateV0,
+		},
+	}
+}
+
+// 将版本0的resources map转换为jobmanager及taskmanager属性
+func upgradeSessionClusterStateV0(ctx context.Context, req resource.UpgradeStateRequest, resp *resource.UpgradeStateResponse) {
+	if req.RawState == nil || req.RawState.JSON == nil {
+		resp.Diagnostics.AddError("Unable to upgrade sessionCluster state", "Prior state is not stored as JSON.")
+		return
+	}
+
+	var rawState map[string]json.RawMessage
+	if err := json.Unmarshal(req.RawState.JSON, &rawState); err != nil {
+		resp.Diagnostics.AddError("Unable to upgrade sessionCluster state", "Could not parse prior state, unexpected error: "+err.Error())
+		return
+	}
+
+	var priorResources map[string]json.RawMessage
+	if raw, ok := rawState["resources"]; ok {
+		if err := json.Unmarshal(raw, &priorResources); err != nil {
+			resp.Diagnostics.AddError("Unable to upgrade sessionCluster state", "Could not parse prior resources, unexpected error: "+err.Error())
+			return
+		}
+	}
+
+	// 只保留AppManager识别的组件,其余key在版本0中同样会被忽略
+	resources := map[string]json.RawMessage{
+		ResourceJobManager:  json.RawMessage("null"),
+		ResourceTaskManager: json.RawMessage("null"),
+	}
+	for k := range resources {
+		if v, ok := priorResources[k]; ok {
+			resources[k] = v
+		}
+	}
+
+	upgraded, err := json.Marshal(resources)
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to upgrade sessionCluster state", "Could not build resources, unexpected error: "+err.Error())
+		return
+	}
+	rawState["resources"] = upgraded
+
+	state, err := json.Marshal(rawState)
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to upgrade sessionCluster state", "Could not build state, unexpected error: "+err.Error())
+		return
+	}
+	resp.DynamicValue = &tfprotov6.DynamicValue{JSON: state}
+}
+
+// 根据集群信息构建状态,Provider侧属性及用户写法沿用prior中的值
+func (r *SessionClusterResource) buildSessionClusterState(sc *client.SessionCluster, prior *SessionClusterResourceModel) *SessionClusterResourceModel {
+	result := buildSessionClusterTfValue(sc)
+	result.RestartPolicy = prior.RestartPolicy
+	result.DeletionPolicy = prior.DeletionPolicy
+	result.ForceDestroy = prior.ForceDestroy
+	result.Labels = flattenLabels(sc.Metadata.Labels, r.provider.DefaultLabels, prior.Labels, r.provider.IgnoreLabelPrefixes)
+	result.Annotations = flattenAnnotations(sc.Metadata.Annotations, prior.Annotations, r.provider.IgnoreAnnotationPrefixes)
+	result.EffectiveLabels = withoutIgnoredKeys(sc.Metadata.Labels, r.provider.IgnoreLabelPrefixes)
+	preserveResourcesNotation(result.Resources, prior.Resources)
+
+	return result
+}
+
+// 构建集群请求参数并合并Provider默认标签
+func (r *SessionClusterResource) buildSessionClusterDTO(sc *SessionClusterResourceModel) *client.SessionCluster {
+	dto := buildSessionClusterDTO(sc)
+	dto.Metadata.Labels = mergeLabels(r.provider.DefaultLabels, sc.Labels)
+	return dto
+}
+
+// effectiveLabels 合并Provider默认标签后集群上应有的标签,不含需要忽略的部分
+func (r *SessionClusterResource) effectiveLabels(labels map[string]string) map[string]string {
+	return withoutIgnoredKeys(mergeLabels(r.provider.DefaultLabels, labels), r.provider.IgnoreLabelPrefixes)
+}
+
 // 将sessionCluster值转换成tf值
 func buildSessionClusterTfValue(sc *client.SessionCluster) *SessionClusterResourceModel {
-	resources := make(map[string]*ResourceSpec)
-	for k, v := range sc.Spec.Resources {
-		resources[k] = &ResourceSpec{
-			Cpu:    types.Number{Value: big.NewFloat(v.Cpu)},
-			Memory: types.String{Value: v.Memory},
-		}
+	resources := &SessionClusterResources{
+		JobManager:  buildResourceSpecTfValue(sc.Spec.Resources[ResourceJobManager]),
+		TaskManager: buildResourceSpecTfValue(sc.Spec.Resources[ResourceTaskManager]),
 	}
 
-	return &SessionClusterResourceModel{
+	result := &SessionClusterResourceModel{
 		ID:                   types.String{Value: sc.Metadata.Id},
 		Namespace:            types.String{Value: sc.Metadata.Namespace},
 		Name:                 types.String{Value: sc.Metadata.Name},
@@ -247,71 +633,293 @@ func buildSessionClusterTfValue(sc *client.SessionCluster) *SessionClusterResour
 		NumberOfTaskManagers: types.Int64{Value: int64(sc.Spec.NumberOfTaskManagers)},
 		Resources:            resources,
 		FlinkConfiguration:   sc.Spec.FlinkConfiguration,
+		FlinkVersion:         types.String{Value: sc.Spec.FlinkVersion},
+		FlinkImageRegistry:   types.String{Value: sc.Spec.FlinkImageRegistry},
+		FlinkImageRepository: types.String{Value: sc.Spec.FlinkImageRepository},
+		StartedAt:            types.String{Null: true},
+		LastUpdateTime:       types.String{Null: true},
+		RunningTaskManagers:  types.Int64{Value: 0},
+		CreatedAt:            types.String{Value: sc.Metadata.CreatedAt},
+		ModifiedAt:           types.String{Value: sc.Metadata.ModifiedAt},
+		ResourceVersion:      types.Int64{Value: int64(sc.Metadata.ResourceVersion)},
+	}
+
+	if running := sc.Status.Running; running != nil {
+		result.StartedAt = types.String{Value: running.StartedAt}
+		result.LastUpdateTime = types.String{Value: running.LastUpdateTime}
+		result.RunningTaskManagers = types.Int64{Value: int64(running.TaskManagerNumbers)}
+	}
+
+	if failure := sc.Status.Failure; failure != nil {
+		result.Failure = buildFailureTfValue(failure)
 	}
+
+	return result
 }
 
-// 将tf值转换成sessionCluster请求参数
-func buildSessionClusterDTO(sc *SessionClusterResourceModel) *client.SessionCluster {
+// 将资源配置转换成tf值
+func buildResourceSpecTfValue(spec *client.ResourceSpec) *ResourceSpec {
+	if spec == nil {
+		return nil
+	}
+
+	return &ResourceSpec{
+		Cpu:    types.Float64{Value: spec.Cpu},
+		Memory: types.String{Value: spec.Memory},
+	}
+}
+
+// 将失败信息转换成tf值
+func buildFailureTfValue(failure *client.Failure) *FailureModel {
+	failedAt := types.String{Null: true}
+	if failure.FailedAt != nil {
+		failedAt = types.String{Value: failure.FailedAt.Format(time.RFC3339)}
+	}
+
+	return &FailureModel{
+		Message:  types.String{Value: failure.Message},
+		Reason:   types.String{Value: failure.Reason},
+		FailedAt: failedAt,
+	}
+}
+
+// 将资源配置转换成请求参数
+func buildResourcesDTO(r *SessionClusterResources) map[string]*client.ResourceSpec {
 	resources := make(map[string]*client.ResourceSpec)
-	for k, v := range sc.Resources {
-		cpu, _ := v.Cpu.Value.Float64()
-		resources[k] = &client.ResourceSpec{
-			Cpu:    cpu,
-			Memory: v.Memory.Value,
+	if r != nil {
+		if spec := r.JobManager; spec != nil {
+			resources[ResourceJobManager] = &client.ResourceSpec{Cpu: spec.Cpu.Value, Memory: spec.Memory.Value}
+		}
+		if spec := r.TaskManager; spec != nil {
+			resources[ResourceTaskManager] = &client.ResourceSpec{Cpu: spec.Cpu.Value, Memory: spec.Memory.Value}
 		}
 	}
+	return resources
+}
 
+// 将tf值转换成sessionCluster请求参数
+func buildSessionClusterDTO(sc *SessionClusterResourceModel) *client.SessionCluster {
 	return &client.SessionCluster{
-		Metadata: &client.SessionClusterMetadata{Name: sc.Name.Value, Namespace: sc.Namespace.Value},
+		Metadata: &client.SessionClusterMetadata{
+			Name:        sc.Name.Value,
+			Namespace:   sc.Namespace.Value,
+			Labels:      sc.Labels,
+			Annotations: sc.Annotations,
+		},
 		Spec: &client.SessionClusterSpec{
 			State:                sc.State.Value,
 			DeploymentTargetName: sc.DeploymentTargetName.Value,
 			FlinkImageTag:        sc.FlinkImageTag.Value,
 			NumberOfTaskManagers: int(sc.NumberOfTaskManagers.Value),
 			FlinkConfiguration:   sc.FlinkConfiguration,
-			Resources:            resources,
+			Resources:            buildResourcesDTO(sc.Resources),
 		},
 	}
 }
 
+// 判断集群配置是否变更,仅Provider侧属性变更时不需要重启集群
+func sessionClusterSpecChanged(state *SessionClusterResourceModel, plan *SessionClusterResourceModel) bool {
+	oldSpec := buildSessionClusterDTO(state).Spec
+	newSpec := buildSessionClusterDTO(plan).Spec
+	if !resourceSpecsEqual(oldSpec.Resources, newSpec.Resources) {
+		return true
+	}
+
+	// 状态及资源不参与比较,资源按数值语义比较
+	oldSpec.State, newSpec.State = "", ""
+	oldSpec.Resources, newSpec.Resources = nil, nil
+
+	return !reflect.DeepEqual(oldSpec, newSpec)
+}
+
+// 判断集群标签及注解是否变更
+func sessionClusterMetadataChanged(state *SessionClusterResourceModel, plan *SessionClusterResourceModel) bool {
+	return !stringMapsEqual(state.Labels, plan.Labels) || !stringMapsEqual(state.Annotations, plan.Annotations)
+}
+
+// 按数值语义比较资源配置
+func resourceSpecsEqual(a map[string]*client.ResourceSpec, b map[string]*client.ResourceSpec) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for k, x := range a {
+		y, ok := b[k]
+		if !ok || !cpuEqual(x.Cpu, y.Cpu) || !memoryEqual(x.Memory, y.Memory) {
+			return false
+		}
+	}
+	return true
+}
+
+// 资源配置语义相同时保留用户配置中的写法,避免单位写法不同导致持续出现变更
+func preserveResourcesNotation(result *SessionClusterResources, prior *SessionClusterResources) {
+	if result == nil || prior == nil {
+		return
+	}
+
+	preserveResourceSpecNotation(result.JobManager, prior.JobManager)
+	preserveResourceSpecNotation(result.TaskManager, prior.TaskManager)
+}
+
+func preserveResourceSpecNotation(result *ResourceSpec, prior *ResourceSpec) {
+	if result == nil || prior == nil {
+		return
+	}
+
+	if cpuEqual(result.Cpu.Value, prior.Cpu.Value) {
+		result.Cpu = prior.Cpu
+	}
+	if memoryEqual(result.Memory.Value, prior.Memory.Value) {
+		result.Memory = prior.Memory
+	}
+}
+
+// UpdateSessionClusterMetadata 更新集群标签及注解,集群配置不变因此不会重启集群
+func (r *SessionClusterResource) UpdateSessionClusterMetadata(state *SessionClusterResourceModel, plan *SessionClusterResourceModel) (*client.SessionCluster, int, error) {
+	namespace := plan.Namespace.Value
+	return r.updateSessionClusterWithRetry(state, int(state.ResourceVersion.Value), func(resourceVersion int) (*client.SessionCluster, int, error) {
+		sc, code, err := r.client.GetSessionCluster(plan.Name.Value, namespace)
+		if err != nil {
+			return nil, code, err
+		}
+
+		// 使用完整对象覆盖,以便删除不再需要的标签及注解,同时保留需要忽略的部分
+		sc.Metadata.Labels = preserveIgnoredKeys(mergeLabels(r.provider.DefaultLabels, plan.Labels), sc.Metadata.Labels, r.provider.IgnoreLabelPrefixes)
+		sc.Metadata.Annotations = preserveIgnoredKeys(plan.Annotations, sc.Metadata.Annotations, r.provider.IgnoreAnnotationPrefixes)
+		sc.Metadata.ResourceVersion = resourceVersion
+		sc.Status = nil
+		return r.client.CreateOrReplaceSessionCluster(sc, namespace)
+	})
+}
+
+// updateSessionClusterWithRetry 携带resourceVersion更新集群。版本冲突时若服务端集群配置仍与expected一致
+// (如仅控制器更新了集群状态),使用最新的resourceVersion重试,否则返回冲突由用户刷新状态后处理
+func (r *SessionClusterResource) updateSessionClusterWithRetry(expected *SessionClusterResourceModel, resourceVersion int, update func(resourceVersion int) (*client.SessionCluster, int, error)) (*client.SessionCluster, int, error) {
+	for attempt := 1; ; attempt++ {
+		sc, code, err := update(resourceVersion)
+		if code != http.StatusConflict || attempt >= ResourceVersionConflictRetries {
+			return sc, code, err
+		}
+
+		remote, _, getErr := r.client.GetSessionCluster(expected.Name.Value, expected.Namespace.Value)
+		if getErr != nil {
+			return sc, code, err
+		}
+		current := r.buildSessionClusterState(remote, expected)
+		if sessionClusterSpecChanged(expected, current) || sessionClusterMetadataChanged(expected, current) {
+			return sc, code, err
+		}
+		resourceVersion = remote.Metadata.ResourceVersion
+	}
+}
+
 // StopSessionCluster 停止SessionCluster
-func (r *SessionClusterResource) StopSessionCluster(namespace string, sessionClusterName string) (*client.SessionCluster, error) {
-	// 停止SessionCluster
+func (r *SessionClusterResource) StopSessionCluster(namespace string, sessionClusterName string, resourceVersion int) (*client.SessionCluster, int, error) {
+	// 停止SessionCluster,resourceVersion为0时不校验版本
 	sc := &client.SessionCluster{
-		Metadata: &client.SessionClusterMetadata{Name: sessionClusterName, Namespace: namespace},
+		Metadata: &client.SessionClusterMetadata{Name: sessionClusterName, Namespace: namespace, ResourceVersion: resourceVersion},
 		Spec:     &client.SessionClusterSpec{State: client.ClusterStopped},
 	}
-	_, _, err := r.client.UpdateSessionCluster(sc, namespace)
+	_, code, err := r.client.UpdateSessionCluster(sc, namespace)
 	if err != nil {
-		return nil, err
+		return nil, code, err
 	}
 
 	// 等待SessionCluster停止
-	sc, _, err = r.client.WaitSessionClusterStateChange(sessionClusterName, client.ClusterStopped, namespace)
+	sc, code, err = r.client.WaitSessionClusterStateChange(sessionClusterName, client.ClusterStopped, namespace)
 	if err != nil {
-		return nil, err
+		return nil, code, err
 	}
 
-	return sc, nil
+	return sc, code, nil
 }
 
 // RunSessionCluster 创建出运行的SessionCluster
-func (r *SessionClusterResource) RunSessionCluster(namespace string, scCfg *client.SessionCluster) (*client.SessionCluster, error) {
+func (r *SessionClusterResource) RunSessionCluster(namespace string, scCfg *client.SessionCluster) (*client.SessionCluster, int, error) {
 	// 写死使用默认日志配置
 	scCfg.Spec.Logging = &client.Logging{Log4jLoggers: map[string]string{"": "INFO"}, LoggingProfile: "default"}
 	// 强制启动
 	scCfg.Spec.State = client.ClusterRunning
 
-	_, _, err := r.client.CreateOrReplaceSessionCluster(scCfg, namespace)
+	_, code, err := r.client.CreateOrReplaceSessionCluster(scCfg, namespace)
 	if err != nil {
-		return nil, err
+		return nil, code, err
 	}
 
 	// 等待集群创建
-	state, _, err := r.client.WaitSessionClusterStateChange(scCfg.Metadata.Name, client.ClusterRunning, namespace)
+	state, code, err := r.client.WaitSessionClusterStateChange(scCfg.Metadata.Name, client.ClusterRunning, namespace)
 	if err != nil {
-		return nil, err
+		return nil, code, err
 	}
 
-	return state, nil
+	return state, code, nil
+}
+
+// SuspendSessionClusterDeployments 暂停运行在SessionCluster上的作业,暂停时会生成savepoint
+func (r *SessionClusterResource) SuspendSessionClusterDeployments(namespace string, sessionClusterName string) (*deploymentStates, error) {
+	states := newDeploymentStates()
+
+	deployments, err := getSessionClusterDeployments(r.client, namespace, sessionClusterName)
+	if err != nil {
+		return states, err
+	}
+
+	// 只处理期望状态为运行中的作业
+	for _, d := range deployments {
+		if d.Spec.State == client.DeploymentRunning {
+			states.set(d.Metadata.Name, client.DeploymentRunning)
+		}
+	}
+
+	for _, name := range states.names {
+		_, err = transitionDeployment(r.client, namespace, name, client.DeploymentSuspended)
+		if err != nil {
+			states.set(name, "unknown (suspend failed)")
+			return states, fmt.Errorf("suspend deployment %s: %w", name, err)
+		}
+		states.set(name, client.DeploymentSuspended)
+	}
+
+	return states, nil
+}
+
+// ResumeDeployments 恢复之前暂停的作业,单个作业失败时继续恢复其余作业
+func (r *SessionClusterResource) ResumeDeployments(namespace string, states *deploymentStates) error {
+	var failed []string
+	for _, name := range states.names {
+		if states.states[name] != client.DeploymentSuspended {
+			continue
+		}
+
+		_, err := transitionDeployment(r.client, namespace, name, client.DeploymentRunning)
+		if err != nil {
+			states.set(name, "unknown (resume failed: "+err.Error()+")")
+			failed = append(failed, name)
+			continue
+		}
+		states.set(name, client.DeploymentRunning)
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to resume deployments: %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// 生成resourceVersion冲突提示,非冲突错误时返回空字符串
+func resourceVersionConflictDetail(kind string, code int) string {
+	if code != http.StatusConflict {
+		return ""
+	}
+	return "\n\nThe " + kind + " was modified outside of Terraform since it was last read. " +
+		"Run `terraform apply -refresh-only` to review the remote changes, then apply again."
+}
+
+// 生成作业状态汇总信息,没有暂停作业时返回空字符串
+func deploymentStatesDetail(states *deploymentStates) string {
+	if states == nil {
+		return ""
+	}
+	return "\n\nSuspended deployments were not resumed and must be resumed manually, " + states.String()
 }
@@ -1,9 +1,21 @@
 package provider
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"git.sofunny.io/data-analysis-public/flink-appmanager-sdk/go/pkg/client"
+	fwresource "github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestAccSessionClusterResource(t *testing.T) {
@@ -17,12 +29,57 @@ func TestAccSessionClusterResource(t *testing.T) {
 				Check: resource.ComposeTestCheckFunc(resource.TestCheckResourceAttr("flink_appmanager_session_cluster.test", "name", "test"),
 					resource.ComposeTestCheckFunc(resource.TestCheckResourceAttr("flink_appmanager_session_cluster.test", "deployment_target_name", "test")),
 					resource.ComposeTestCheckFunc(resource.TestCheckResourceAttr("flink_appmanager_session_cluster.test", "state", "RUNNING")),
+					resource.ComposeTestCheckFunc(resource.TestCheckResourceAttr("flink_appmanager_session_cluster.test", "labels.app", "test")),
 				),
 			},
 		},
 	})
 }
 
+func TestSessionClusterStateUpgradeV0(t *testing.T) {
+	ctx := context.Background()
+	r := &SessionClusterResource{}
+	schema, diags := r.GetSchema(ctx)
+	if diags.HasError() {
+		t.Fatalf("unexpected schema diagnostics: %v", diags)
+	}
+
+	req := fwresource.UpgradeStateRequest{
+		RawState: &tfprotov6.RawState{JSON: []byte(`{
+			"id": "1", "namespace": "test", "name": "test", "state": "RUNNING",
+			"flink_image_tag": "1.14.4-scala_2.12-java11-1", "number_of_task_managers": 1,
+			"flink_configuration": {"state.backend": "true"},
+			"resources": {
+				"jobmanager": {"cpu": 1, "memory": "1G"},
+				"taskmanger": {"cpu": 2, "memory": "2G"}
+			}
+		}`)},
+	}
+	resp := &fwresource.UpgradeStateResponse{}
+	upgradeSessionClusterStateV0(ctx, req, resp)
+	if resp.Diagnostics.HasError() {
+		t.Fatalf("unexpected upgrade diagnostics: %v", resp.Diagnostics)
+	}
+
+	value, err := resp.DynamicValue.Unmarshal(schema.Type().TerraformType(ctx))
+	if err != nil {
+		t.Fatalf("upgraded state does not match schema: %s", err)
+	}
+
+	var model SessionClusterResourceModel
+	diags = tfsdk.State{Schema: schema, Raw: value}.Get(ctx, &model)
+	if diags.HasError() {
+		t.Fatalf("unexpected state diagnostics: %v", diags)
+	}
+
+	if model.Resources.JobManager == nil || model.Resources.JobManager.Memory.Value != "1G" || model.Resources.JobManager.Cpu.Value != 1 {
+		t.Errorf("jobmanager resources not migrated: %+v", model.Resources.JobManager)
+	}
+	if model.Resources.TaskManager != nil {
+		t.Errorf("unknown resource key migrated into taskmanager: %+v", model.Resources.TaskManager)
+	}
+}
+
 func testAccSessionClusterResourceConfig(name string, deploymentTargetName string) string {
 	return fmt.Sprintf(`
 resource "flink_appmanager_namespace" "test" {
@@ -53,6 +110,10 @@ resource "flink_appmanager_session_cluster" "test" {
   deployment_target_name = flink_appmanager_deployment_target.test.name
   flink_image_tag = "1.14.4-scala_2.12-java11-1"
   number_of_task_managers = 1
+  restart_policy = "suspend_deployments"
+  labels = {
+    "app" = "test"
+  }
   flink_configuration = {
     "high-availability": "flink-kubernetes"
     "execution.checkpointing.externalized-checkpoint-retention" = "RETAIN_ON_CANCELLATION"
@@ -73,3 +134,264 @@ resource "flink_appmanager_session_cluster" "test" {
 }
 `, name, deploymentTargetName)
 }
+
+func TestUpdateSessionClusterWithRetry(t *testing.T) {
+	remoteImageTag := "1.14.4-scala_2.12-java11-1"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(fmt.Sprintf(`{
+			"metadata": {"id": "sc-1", "name": "test", "namespace": "default", "resourceVersion": 5},
+			"spec": {"state": "RUNNING", "flinkImageTag": %q, "numberOfTaskManagers": 1},
+			"status": {"state": "RUNNING"}
+		}`, remoteImageTag)))
+	}))
+	defer server.Close()
+
+	r := &SessionClusterResource{
+		client:   client.SetUp(client.Config{Endpoint: server.URL}),
+		provider: &FlinkAppManagerProviderData{},
+	}
+	expected := r.buildSessionClusterState(&client.SessionCluster{
+		Metadata: &client.SessionClusterMetadata{Id: "sc-1", Name: "test", Namespace: "default", ResourceVersion: 1},
+		Spec:     &client.SessionClusterSpec{State: client.ClusterRunning, FlinkImageTag: "1.14.4-scala_2.12-java11-1", NumberOfTaskManagers: 1},
+		Status:   &client.SessionClusterStatus{State: client.ClusterRunning},
+	}, &SessionClusterResourceModel{})
+
+	var versions []int
+	update := func(resourceVersion int) (*client.SessionCluster, int, error) {
+		versions = append(versions, resourceVersion)
+		if resourceVersion != 5 {
+			return nil, http.StatusConflict, fmt.Errorf("conflict")
+		}
+		return &client.SessionCluster{}, http.StatusOK, nil
+	}
+
+	// only the status changed on the server, the update is retried with the latest version
+	_, code, err := r.updateSessionClusterWithRetry(expected, 1, update)
+	if err != nil || code != http.StatusOK || !reflect.DeepEqual(versions, []int{1, 5}) {
+		t.Errorf("expected a retry with the latest version, got code %d, versions %v, error %v", code, versions, err)
+	}
+
+	// the spec was changed outside of Terraform, the conflict is returned
+	remoteImageTag = "1.13.6-scala_2.12-java8-1"
+	versions = nil
+	_, code, err = r.updateSessionClusterWithRetry(expected, 1, update)
+	if err == nil || code != http.StatusConflict || !reflect.DeepEqual(versions, []int{1}) {
+		t.Errorf("expected the conflict to be returned, got code %d, versions %v, error %v", code, versions, err)
+	}
+}
+
+// newSessionClusterTestServer fakes AppManager and records the last replaced session cluster
+func newSessionClusterTestServer(t *testing.T, remoteLabels string, put *map[string]interface{}) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/ui/config.json" {
+			_, _ = w.Write([]byte(`{"flinkImageTagsAndRepository": {
+				"1.14.4-scala_2.12-java11-1": {"flinkVersion": "1.14", "image": {"repository": "registry/flink", "pullPolicy": "Always"}}
+			}}`))
+			return
+		}
+		if r.Method == http.MethodPut {
+			// echo the replaced object back with its status
+			var sc map[string]interface{}
+			_ = json.NewDecoder(r.Body).Decode(&sc)
+			sc["status"] = map[string]string{"state": client.ClusterRunning}
+			*put = sc
+			_ = json.NewEncoder(w).Encode(sc)
+			return
+		}
+		_, _ = w.Write([]byte(fmt.Sprintf(`{
+			"metadata": {"id": "sc-1", "name": "test", "namespace": "default", "resourceVersion": 1, "labels": %s},
+			"spec": {"state": "RUNNING", "flinkImageTag": "1.14.4-scala_2.12-java11-1", "numberOfTaskManagers": 1,
+				"resources": {"taskmanager": {"cpu": 1, "memory": "1G"}}},
+			"status": {"state": "RUNNING"}
+		}`, remoteLabels)))
+	}))
+}
+
+// testSessionClusterState matches the session cluster served by newSessionClusterTestServer
+func testSessionClusterState() *SessionClusterResourceModel {
+	return &SessionClusterResourceModel{
+		ID:                   types.String{Value: "sc-1"},
+		Namespace:            types.String{Value: "default"},
+		Name:                 types.String{Value: "test"},
+		State:                types.String{Value: client.ClusterRunning},
+		DeploymentTargetName: types.String{Null: true},
+		FlinkImageTag:        types.String{Value: "1.14.4-scala_2.12-java11-1"},
+		NumberOfTaskManagers: types.Int64{Value: 1},
+		Resources:            &SessionClusterResources{TaskManager: &ResourceSpec{Cpu: types.Float64{Value: 1}, Memory: types.String{Value: "1G"}}},
+		Labels:               map[string]string{"app": "test"},
+		EffectiveLabels:      map[string]string{"app": "test"},
+		RestartPolicy:        types.String{Null: true},
+		DeletionPolicy:       types.String{Null: true},
+		ForceDestroy:         types.Bool{Null: true},
+		FlinkVersion:         types.String{Null: true},
+		FlinkImageRegistry:   types.String{Null: true},
+		FlinkImageRepository: types.String{Null: true},
+		StartedAt:            types.String{Null: true},
+		LastUpdateTime:       types.String{Null: true},
+		RunningTaskManagers:  types.Int64{Value: 1},
+		CreatedAt:            types.String{Null: true},
+		ModifiedAt:           types.String{Null: true},
+		ResourceVersion:      types.Int64{Value: 1},
+	}
+}
+
+// runSessionClusterUpdate runs Update from prior to planned and returns the saved state
+func runSessionClusterUpdate(t *testing.T, r *SessionClusterResource, prior *SessionClusterResourceModel, planned *SessionClusterResourceModel) *SessionClusterResourceModel {
+	t.Helper()
+	ctx := context.Background()
+	schema, diags := r.GetSchema(ctx)
+	if diags.HasError() {
+		t.Fatalf("unexpected schema diagnostics: %v", diags)
+	}
+
+	state := tfsdk.State{Schema: schema}
+	plan := tfsdk.State{Schema: schema}
+	if diags = state.Set(ctx, prior); diags.HasError() {
+		t.Fatalf("unexpected state diagnostics: %v", diags)
+	}
+	if diags = plan.Set(ctx, planned); diags.HasError() {
+		t.Fatalf("unexpected plan diagnostics: %v", diags)
+	}
+
+	req := fwresource.UpdateRequest{
+		Config: tfsdk.Config{Schema: schema, Raw: plan.Raw},
+		Plan:   tfsdk.Plan{Schema: schema, Raw: plan.Raw},
+		State:  state,
+	}
+	resp := &fwresource.UpdateResponse{State: tfsdk.State{Schema: schema, Raw: state.Raw}}
+	r.Update(ctx, req, resp)
+	if resp.Diagnostics.HasError() {
+		t.Fatalf("unexpected update diagnostics: %v", resp.Diagnostics)
+	}
+
+	var result SessionClusterResourceModel
+	if diags = resp.State.Get(ctx, &result); diags.HasError() {
+		t.Fatalf("unexpected result diagnostics: %v", diags)
+	}
+	return &result
+}
+
+func TestSessionClusterUpdateKeepsConfiguredNotation(t *testing.T) {
+	var put map[string]interface{}
+	server := newSessionClusterTestServer(t, `{"app": "test"}`, &put)
+	defer server.Close()
+
+	r := &SessionClusterResource{
+		client:   client.SetUp(client.Config{Endpoint: server.URL}),
+		provider: &FlinkAppManagerProviderData{},
+	}
+
+	// the memory notation changes in the same apply as the labels
+	prior := testSessionClusterState()
+	planned := *prior
+	planned.Resources = &SessionClusterResources{TaskManager: &ResourceSpec{Cpu: types.Float64{Value: 1}, Memory: types.String{Value: "1024m"}}}
+	planned.Labels = map[string]string{"app": "test", "team": "data"}
+
+	result := runSessionClusterUpdate(t, r, prior, &planned)
+	if got := result.Resources.TaskManager.Memory.Value; got != "1024m" {
+		t.Errorf("memory saved as %q, want the planned notation 1024m", got)
+	}
+	if !reflect.DeepEqual(result.Labels, planned.Labels) {
+		t.Errorf("labels saved as %v, want %v", result.Labels, planned.Labels)
+	}
+}
+
+func TestSessionClusterUpdateAppliesDefaultLabels(t *testing.T) {
+	var put map[string]interface{}
+	server := newSessionClusterTestServer(t, `{"app": "test", "com.xmfunny.flink.cluster": "1"}`, &put)
+	defer server.Close()
+
+	// a default label was added to the provider after the cluster was created
+	r := &SessionClusterResource{
+		client: client.SetUp(client.Config{Endpoint: server.URL}),
+		provider: &FlinkAppManagerProviderData{
+			DefaultLabels:       map[string]string{"env": "prod"},
+			IgnoreLabelPrefixes: []string{"com.xmfunny.flink"},
+		},
+	}
+	prior := testSessionClusterState()
+	planned := *prior
+	planned.EffectiveLabels = map[string]string{"app": "test", "env": "prod"}
+
+	result := runSessionClusterUpdate(t, r, prior, &planned)
+	if put == nil {
+		t.Fatal("expected the cluster metadata to be replaced")
+	}
+	expected := map[string]interface{}{"app": "test", "env": "prod", "com.xmfunny.flink.cluster": "1"}
+	if labels := put["metadata"].(map[string]interface{})["labels"]; !reflect.DeepEqual(labels, expected) {
+		t.Errorf("replaced labels %v, want %v", labels, expected)
+	}
+	if !reflect.DeepEqual(result.Labels, prior.Labels) {
+		t.Errorf("labels saved as %v, want %v", result.Labels, prior.Labels)
+	}
+	if !reflect.DeepEqual(result.EffectiveLabels, planned.EffectiveLabels) {
+		t.Errorf("effective labels saved as %v, want %v", result.EffectiveLabels, planned.EffectiveLabels)
+	}
+}
+
+func TestSessionClusterUpdateResumesDeploymentsWhenStopFails(t *testing.T) {
+	desired := client.DeploymentRunning
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/api/v1/namespaces/default/deployments":
+			_, _ = w.Write([]byte(fmt.Sprintf(`{"items": [{
+				"metadata": {"id": "d-1", "name": "job", "namespace": "default"},
+				"spec": {"state": %q, "sessionClusterName": "test"}
+			}]}`, desired)))
+		case r.URL.Path == "/api/v1/namespaces/default/deployments/job" && r.Method == http.MethodPatch:
+			var d client.Deployment
+			_ = json.NewDecoder(r.Body).Decode(&d)
+			desired = d.Spec.State
+			_ = json.NewEncoder(w).Encode(d)
+		case r.URL.Path == "/api/v1/namespaces/default/deployments/job":
+			_, _ = w.Write([]byte(fmt.Sprintf(`{
+				"metadata": {"id": "d-1", "name": "job", "namespace": "default", "resourceVersion": 1},
+				"spec": {"state": %[1]q, "sessionClusterName": "test"},
+				"status": {"state": %[1]q}
+			}`, desired)))
+		case r.Method == http.MethodPatch:
+			// the cluster cannot be stopped
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = w.Write([]byte(`{"message": "stop failed"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	r := &SessionClusterResource{
+		client:   client.SetUp(client.Config{Endpoint: server.URL, Interval: time.Millisecond, Timeout: time.Second}),
+		provider: &FlinkAppManagerProviderData{},
+	}
+	schema, diags := r.GetSchema(context.Background())
+	if diags.HasError() {
+		t.Fatalf("unexpected schema diagnostics: %v", diags)
+	}
+
+	prior := testSessionClusterState()
+	planned := *prior
+	planned.NumberOfTaskManagers = types.Int64{Value: 2}
+	planned.RestartPolicy = types.String{Value: SessionClusterRestartPolicySuspendDeployments}
+
+	state := tfsdk.State{Schema: schema}
+	plan := tfsdk.State{Schema: schema}
+	_ = state.Set(context.Background(), prior)
+	_ = plan.Set(context.Background(), &planned)
+	resp := &fwresource.UpdateResponse{State: tfsdk.State{Schema: schema, Raw: state.Raw}}
+	r.Update(context.Background(), fwresource.UpdateRequest{
+		Config: tfsdk.Config{Schema: schema, Raw: plan.Raw},
+		Plan:   tfsdk.Plan{Schema: schema, Raw: plan.Raw},
+		State:  state,
+	}, resp)
+
+	if !resp.Diagnostics.HasError() {
+		t.Fatal("expected the failed stop to be reported")
+	}
+	if desired != client.DeploymentRunning {
+		t.Errorf("expected the suspended deployment to be resumed, its desired state is %s", desired)
+	}
+	if detail := resp.Diagnostics[0].Detail(); !strings.Contains(detail, "job: "+client.DeploymentRunning) {
+		t.Errorf("expected the resulting deployment states to be reported, got %q", detail)
+	}
+}
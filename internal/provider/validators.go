@@ -0,0 +1,112 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"strings"
+)
+
+var _ tfsdk.AttributeValidator = stringOneOfValidator{}
+var _ tfsdk.AttributeValidator = cpuQuantityValidator{}
+var _ tfsdk.AttributeValidator = memoryQuantityValidator{}
+
+// stringOneOfValidator 校验字符串取值在给定范围内
+type stringOneOfValidator struct {
+	values []string
+}
+
+func stringOneOf(values ...string) tfsdk.AttributeValidator {
+	return stringOneOfValidator{values: values}
+}
+
+func (v stringOneOfValidator) Description(_ context.Context) string {
+	return fmt.Sprintf("value must be one of: %s", strings.Join(v.values, ", "))
+}
+
+func (v stringOneOfValidator) MarkdownDescription(ctx context.Context) string {
+	return v.Description(ctx)
+}
+
+func (v stringOneOfValidator) Validate(ctx context.Context, req tfsdk.ValidateAttributeRequest, resp *tfsdk.ValidateAttributeResponse) {
+	var value types.String
+	resp.Diagnostics.Append(tfsdk.ValueAs(ctx, req.AttributeConfig, &value)...)
+	if resp.Diagnostics.HasError() || value.Null || value.Unknown {
+		return
+	}
+
+	for _, v := range v.values {
+		if value.Value == v {
+			return
+		}
+	}
+
+	resp.Diagnostics.AddAttributeError(
+		req.AttributePath,
+		"Invalid Attribute Value",
+		fmt.Sprintf("Expected %s, got: %q", v.Description(ctx), value.Value),
+	)
+}
+
+// cpuQuantityValidator 校验CPU数量大于0且精度不超过毫核
+type cpuQuantityValidator struct{}
+
+func cpuQuantity() tfsdk.AttributeValidator {
+	return cpuQuantityValidator{}
+}
+
+func (v cpuQuantityValidator) Description(_ context.Context) string {
+	return "value must be greater than 0 with at most millicore (0.001) precision"
+}
+
+func (v cpuQuantityValidator) MarkdownDescription(ctx context.Context) string {
+	return v.Description(ctx)
+}
+
+func (v cpuQuantityValidator) Validate(ctx context.Context, req tfsdk.ValidateAttributeRequest, resp *tfsdk.ValidateAttributeResponse) {
+	var value types.Float64
+	resp.Diagnostics.Append(tfsdk.ValueAs(ctx, req.AttributeConfig, &value)...)
+	if resp.Diagnostics.HasError() || value.Null || value.Unknown {
+		return
+	}
+
+	if !validCpu(value.Value) {
+		resp.Diagnostics.AddAttributeError(
+			req.AttributePath,
+			"Invalid Attribute Value",
+			fmt.Sprintf("Expected %s, got: %g", v.Description(ctx), value.Value),
+		)
+	}
+}
+
+// memoryQuantityValidator 校验内存大小格式
+type memoryQuantityValidator struct{}
+
+func memoryQuantity() tfsdk.AttributeValidator {
+	return memoryQuantityValidator{}
+}
+
+func (v memoryQuantityValidator) Description(_ context.Context) string {
+	return "value must be a memory quantity such as 1G, 1Gi or 1024m"
+}
+
+func (v memoryQuantityValidator) MarkdownDescription(ctx context.Context) string {
+	return v.Description(ctx)
+}
+
+func (v memoryQuantityValidator) Validate(ctx context.Context, req tfsdk.ValidateAttributeRequest, resp *tfsdk.ValidateAttributeResponse) {
+	var value types.String
+	resp.Diagnostics.Append(tfsdk.ValueAs(ctx, req.AttributeConfig, &value)...)
+	if resp.Diagnostics.HasError() || value.Null || value.Unknown {
+		return
+	}
+
+	if _, err := parseMemory(value.Value); err != nil {
+		resp.Diagnostics.AddAttributeError(
+			req.AttributePath,
+			"Invalid Attribute Value",
+			err.Error(),
+		)
+	}
+}